@@ -0,0 +1,51 @@
+import _ from 'lodash';
+import responseHandler from '../responseHandler';
+
+const ITEMS_COUNT = 1000;
+const HOSTS_COUNT = 100;
+const POINTS_COUNT = 100;
+
+let items = _.map(_.range(ITEMS_COUNT), i => {
+  const hostid = String(10000 + i % HOSTS_COUNT);
+  return {
+    itemid: String(20000 + i),
+    name: `Item ${i}`,
+    hostid: hostid,
+    hosts: [{ hostid: hostid, name: `Host ${hostid}` }]
+  };
+});
+
+let history = _.flatten(_.map(items, item => {
+  return _.map(_.range(POINTS_COUNT), j => {
+    return { itemid: item.itemid, clock: String(1498409636 + j * 60), value: String(Math.random()), ns: '0' };
+  });
+}));
+
+// Previous implementation, searches item and host for each series
+function convertHistoryWithFind(history, items) {
+  let grouped_history = _.groupBy(history, 'itemid');
+  let hosts = _.uniqBy(_.flatten(_.map(items, 'hosts')), 'hostid');
+
+  return _.map(grouped_history, (hist, itemid) => {
+    let item = _.find(items, {'itemid': itemid});
+    let host = _.find(hosts, {'hostid': item.hostid});
+    return {
+      target: host.name + ": " + item.name,
+      datapoints: _.map(hist, point => [Number(point.value), point.clock * 1000])
+    };
+  });
+}
+
+module.exports = [
+  {
+    name: 'convertHistory',
+    tests: {
+      'handleHistory()': () => {
+        responseHandler.handleHistory(history, items);
+      },
+      'convertHistoryWithFind()': () => {
+        convertHistoryWithFind(history, items);
+      }
+    }
+  }
+];
@@ -1,5 +1,6 @@
 import _ from 'lodash';
 import { migrateDSConfig } from './migrations';
+import * as c from './constants';
 
 const SUPPORTED_SQL_DS = ['mysql', 'postgres', 'influxdb'];
 
@@ -18,6 +19,7 @@ const defaultConfig = {
   alertingMinSeverity: 3,
   disableReadOnlyUsersAck: false,
   zabbixVersion: 3,
+  defaultMinSeverity: 3,
 };
 
 export class ZabbixDSConfigController {
@@ -32,6 +34,7 @@ export class ZabbixDSConfigController {
     this.dbConnectionDatasourceId = this.current.jsonData.dbConnectionDatasourceId;
     this.dbDataSources = this.getSupportedDBDataSources();
     this.zabbixVersions = _.cloneDeep(zabbixVersions);
+    this.triggerSeverity = c.TRIGGER_SEVERITY;
     this.autoDetectZabbixVersion();
     if (!this.dbConnectionDatasourceId) {
       this.loadCurrentDBDatasource();
@@ -2,12 +2,18 @@
 export const DATAPOINT_VALUE = 0;
 export const DATAPOINT_TS = 1;
 
+// Downsampling methods
+export const DOWNSAMPLING_CONSOLIDATE = '';
+export const DOWNSAMPLING_LTTB = 'lttb';
+
 // Editor modes
 export const MODE_METRICS = 0;
 export const MODE_ITSERVICE = 1;
 export const MODE_TEXT = 2;
 export const MODE_ITEMID = 3;
 export const MODE_TRIGGERS = 4;
+export const MODE_PROXIES = 5;
+export const MODE_HOST_GROUPS = 6;
 
 // Triggers severity
 export const SEV_NOT_CLASSIFIED = 0;
@@ -21,10 +27,39 @@ export const SHOW_ALL_TRIGGERS = [0, 1];
 export const SHOW_ALL_EVENTS = [0, 1];
 export const SHOW_OK_EVENTS = 1;
 
+// Item value type
+export const ZBX_VALUE_TYPE_UINT64 = '3';
+
+// Item state
+export const ZBX_ITEM_STATE_NOT_SUPPORTED = '1';
+
+// Item tag filter operators (Zabbix 5.4+)
+export const ZBX_TAG_OPERATOR_LIKE = 0;
+export const ZBX_TAG_OPERATOR_EQUAL = 1;
+export const ZBX_TAG_OPERATOR_NOT_LIKE = 2;
+export const ZBX_TAG_OPERATOR_NOT_EQUAL = 3;
+export const ZBX_TAG_OPERATOR_EXISTS = 4;
+export const ZBX_TAG_OPERATOR_NOT_EXISTS = 5;
+
+// Zabbix 5.4 converts applications to item tags with this name
+export const ZBX_APPLICATION_TAG = 'Application';
+
+// Maintenance
+export const ZBX_MAINTENANCE_ONE_TIME = '0';
+export const ZBX_MAINTENANCE_DAILY = '2';
+export const ZBX_MAINTENANCE_WEEKLY = '3';
+export const ZBX_MAINTENANCE_MONTHLY = '4';
+export const ZBX_MAINTENANCE_LAST_WEEK = '5';
+export const ZBX_HOST_MAINTENANCE_ON = '1';
+
+// Zabbix API error codes
+export const ZBX_API_ERROR_INVALID_PARAMS = -32602;
+
 // Acknowledge
 export const ZBX_ACK_ACTION_NONE = 0;
 export const ZBX_ACK_ACTION_ACK = 2;
 export const ZBX_ACK_ACTION_ADD_MESSAGE = 4;
+export const ZBX_ACK_ACTION_CHANGE_SEVERITY = 8;
 
 export const TRIGGER_SEVERITY = [
   {val: 0, text: 'Not classified'},
@@ -35,6 +70,13 @@ export const TRIGGER_SEVERITY = [
   {val: 5, text: 'Disaster'}
 ];
 
+// Host status
+export const ZBX_HOST_MONITORED = '0';
+
+// Proxy status
+export const ZBX_PROXY_ACTIVE = '5';
+export const ZBX_PROXY_PASSIVE = '6';
+
 /** Minimum interval for SLA over time (1 hour) */
 export const MIN_SLA_INTERVAL = 3600;
 
@@ -3,6 +3,8 @@ import * as utils from './utils';
 import ts, { groupBy_perf as groupBy } from './timeseries';
 
 let downsampleSeries = ts.downsample;
+let downsampleLTTB = ts.downsampleLTTB;
+let aggregateSeries = ts.aggregateSeries;
 let groupBy_exported = (interval, groupFunc, datapoints) => groupBy(datapoints, interval, groupFunc);
 let sumSeries = ts.sumSeries;
 let delta = ts.delta;
@@ -63,12 +65,137 @@ function transformNull(n, datapoints) {
   });
 }
 
+/**
+ * Extract numeric field from JSON values, ie for text items collected by HTTP agent.
+ */
+function jsonField(path, datapoints) {
+  return _.map(datapoints, point => {
+    return [
+      extractJSONField(point[0], path),
+      point[1]
+    ];
+  });
+}
+
+function extractJSONField(value, path) {
+  let obj;
+  try {
+    obj = typeof value === 'string' ? JSON.parse(value) : value;
+  } catch (e) {
+    return null;
+  }
+
+  let field = _.get(obj, path);
+  if (field === null || field === undefined || field === '' || typeof field === 'object') {
+    return null;
+  }
+  field = Number(field);
+  return isNaN(field) ? null : field;
+}
+
+/**
+ * Keep only bits set in the mask. Works with 32-bit values.
+ */
+function mask(m, datapoints) {
+  return _.map(datapoints, point => {
+    return [
+      (point[0] !== null) ? (point[0] & m) >>> 0 : null,
+      point[1]
+    ];
+  });
+}
+
+/**
+ * Add rolling z-score series next to each source series, so original metric is kept on the panel.
+ */
+function anomalyScore(n, timeseries) {
+  return _.flatten(_.map(timeseries, series => {
+    let scoreSeries = Object.assign({}, series, {
+      target: `${series.target} anomaly`,
+      datapoints: ts.anomalyScore(series.datapoints, n),
+      tags: Object.assign({}, series.tags, { anomaly: 'true' })
+    });
+    return [series, scoreSeries];
+  }));
+}
+
+/**
+ * Split each series into 0/1 series, one for each of given bit positions.
+ * @param {string} bits comma-separated list of bit positions, ie '0,3,7'
+ */
+function bit(bits, timeseries) {
+  let positions = _.map(String(bits).split(','), n => Number(n.trim()));
+  return _.flatten(_.map(timeseries, series => {
+    return _.map(positions, n => {
+      // Keep series tags, so labels functions could be applied to bit series
+      return Object.assign({}, series, {
+        target: `${series.target} bit ${n}`,
+        datapoints: _.map(series.datapoints, point => [getBit(point[0], n), point[1]]),
+        tags: Object.assign({}, series.tags, { bit: String(n) })
+      });
+    });
+  }));
+}
+
+function getBit(value, n) {
+  if (value === null) {
+    return null;
+  }
+  // Use division instead of bitwise shift for supporting values wider than 32 bits
+  return Math.floor(value / Math.pow(2, n)) % 2;
+}
+
+/**
+ * Add label extracted by regex from series tag (item name, key or host).
+ * First capture group is used if present, otherwise whole match.
+ */
+function extractLabel(field, regex, labelName, timeseries) {
+  let pattern = utils.isRegex(regex) ? utils.buildRegex(regex) : utils.compileRegex(regex);
+  return _.map(timeseries, series => {
+    let source = series.tags ? series.tags[field] : null;
+    // Pattern with global or sticky flag keeps lastIndex between exec() calls
+    pattern.lastIndex = 0;
+    let matches = source ? pattern.exec(source) : null;
+    if (matches) {
+      let label = matches.length > 1 ? matches[1] : matches[0];
+      series.tags = Object.assign({}, series.tags, { [labelName]: label });
+    }
+    return series;
+  });
+}
+
+/**
+ * Aggregate series with the same value of label into one series named by label value.
+ * Series without label are returned as is.
+ */
+function groupByLabel(label, aggregateFunc, timeseries) {
+  let aggFunc = aggregationFunctions[aggregateFunc];
+  let [labeled, unlabeled] = _.partition(timeseries, series => series.tags && series.tags[label] !== undefined);
+  let groups = _.groupBy(labeled, series => series.tags[label]);
+  let grouped = _.map(groups, (series, labelValue) => {
+    return {
+      target: labelValue,
+      datapoints: ts.aggregateSeries(_.map(series, 'datapoints'), aggFunc),
+      tags: { [label]: labelValue }
+    };
+  });
+  return grouped.concat(unlabeled);
+}
+
 function sortSeries(direction, timeseries) {
   return _.orderBy(timeseries, [function (ts) {
     return ts.target.toLowerCase();
   }], direction);
 }
 
+function sortByValue(orderByFunc, direction, timeseries) {
+  let orderByCallback = aggregationFunctions[orderByFunc];
+  return _.orderBy(timeseries, [function (ts) {
+    let values = _.map(ts.datapoints, point => point[0]);
+    return orderByCallback(values);
+  }], direction);
+}
+
 function setAlias(alias, timeseries) {
   timeseries.target = alias;
   return timeseries;
@@ -93,7 +220,7 @@ function setAliasByRegex(alias, timeseries) {
 }
 
 function extractText(str, pattern) {
-  var extractPattern = new RegExp(pattern);
+  var extractPattern = utils.compileRegex(pattern);
   var extractedValue = extractPattern.exec(str);
   extractedValue = extractedValue[0];
   return extractedValue;
@@ -151,7 +278,10 @@ let metricFunctions = {
   rate: rate,
   movingAverage: simpleMovingAverage,
   exponentialMovingAverage: expMovingAverage,
+  anomalyScore: anomalyScore,
   transformNull: transformNull,
+  jsonField: jsonField,
+  mask: mask,
   aggregateBy: aggregateByWrapper,
   // Predefined aggs
   percentile: percentile,
@@ -167,6 +297,10 @@ let metricFunctions = {
   top: _.partial(limit, 'top'),
   bottom: _.partial(limit, 'bottom'),
   sortSeries: sortSeries,
+  sortByValue: sortByValue,
+  bit: bit,
+  extractLabel: extractLabel,
+  groupByLabel: groupByLabel,
   timeShift: timeShift,
   setAlias: setAlias,
   setAliasByRegex: setAliasByRegex,
@@ -184,6 +318,8 @@ let aggregationFunctions = {
 
 export default {
   downsampleSeries: downsampleSeries,
+  downsampleLTTB: downsampleLTTB,
+  aggregateSeries: aggregateSeries,
   groupBy: groupBy_exported,
   AVERAGE: AVERAGE,
   MIN: MIN,
@@ -27,8 +27,11 @@ export class ZabbixDatasource {
     // General data source settings
     this.name             = instanceSettings.name;
     this.url              = instanceSettings.url;
+    this.access           = instanceSettings.access;
     this.basicAuth        = instanceSettings.basicAuth;
     this.withCredentials  = instanceSettings.withCredentials;
+    this.pluginVersion    = _.get(instanceSettings, 'meta.info.version');
+    this.pluginBuild      = _.get(instanceSettings, 'meta.info.build.hash');
 
     const jsonData = migrations.migrateDSConfig(instanceSettings.jsonData);
 
@@ -45,15 +48,39 @@ export class ZabbixDatasource {
     var ttl = jsonData.cacheTTL || '1h';
     this.cacheTTL = utils.parseInterval(ttl);
 
+    // Retry failed requests (network errors and 5xx responses)
+    this.requestRetries = Number(jsonData.requestRetries) || 0;
+
+    // Log requests slower than threshold, disabled if not set
+    this.slowRequestThreshold = jsonData.slowRequestThreshold ? utils.parseInterval(jsonData.slowRequestThreshold) : 0;
+
+    // Log all requests and responses to browser console, credentials are redacted
+    this.traceRequests = jsonData.traceRequests;
+
     // Alerting options
     this.alertingEnabled =     jsonData.alerting;
     this.addThresholds =       jsonData.addThresholds;
     this.alertingMinSeverity = jsonData.alertingMinSeverity || c.SEV_WARNING;
 
+    // Default query options, applied if not set in the query
+    this.defaultConsolidateBy = jsonData.defaultConsolidateBy;
+    this.defaultMinSeverity = jsonData.defaultMinSeverity;
+    this.caseInsensitiveFilters = jsonData.caseInsensitiveFilters;
+    this.useTechnicalHostName = jsonData.useTechnicalHostName;
+    this.inventoryFields = _.compact(_.map(_.split(jsonData.inventoryLabels, ','), _.trim));
+
     // Other options
     this.disableReadOnlyUsersAck = jsonData.disableReadOnlyUsersAck;
     this.zabbixVersion = jsonData.zabbixVersion || DEFAULT_ZABBIX_VERSION;
 
+    // Zabbix API compatibility options
+    this.jsonrpcVersion = jsonData.jsonrpcVersion;
+    this.apiContentType = jsonData.apiContentType;
+    this.sendNullAuth = jsonData.sendNullAuth;
+
+    // Forward Grafana org and user as request headers
+    this.requestHeaders = getContextHeaders(jsonData.orgIdHeader, jsonData.userLoginHeader);
+
     // Direct DB Connection options
     this.enableDirectDBConnection = jsonData.dbConnectionEnable || false;
     this.dbConnectionDatasourceId = jsonData.dbConnectionDatasourceId;
@@ -62,11 +89,22 @@ export class ZabbixDatasource {
 
     let zabbixOptions = {
       url: this.url,
+      access: this.access,
       username: this.username,
       password: this.password,
       basicAuth: this.basicAuth,
       withCredentials: this.withCredentials,
       zabbixVersion: this.zabbixVersion,
+      jsonrpcVersion: this.jsonrpcVersion,
+      apiContentType: this.apiContentType,
+      sendNullAuth: this.sendNullAuth,
+      requestHeaders: this.requestHeaders,
+      requestRetries: this.requestRetries,
+      slowRequestThreshold: this.slowRequestThreshold,
+      traceRequests: this.traceRequests,
+      caseInsensitiveFilters: this.caseInsensitiveFilters,
+      useTechnicalHostName: this.useTechnicalHostName,
+      inventoryFields: this.inventoryFields,
       cacheTTL: this.cacheTTL,
       enableDirectDBConnection: this.enableDirectDBConnection,
       dbConnectionDatasourceId: this.dbConnectionDatasourceId,
@@ -156,13 +194,26 @@ export class ZabbixDatasource {
       } else if (target.mode === c.MODE_TRIGGERS) {
         // Triggers mode
         return this.queryTriggersData(target, timeRange);
+      } else if (target.mode === c.MODE_PROXIES) {
+        // Proxies mode
+        return this.queryProxiesData(target);
+      } else if (target.mode === c.MODE_HOST_GROUPS) {
+        // Host groups overview mode
+        return this.queryHostGroupsData(target);
       } else {
         return [];
       }
     });
 
+    // Keep refId of the query in each result to match series and tables with queries
+    let targetPromises = _.map(promises, (targetPromise, index) => {
+      return Promise.all(_.flatten([targetPromise]))
+        .then(_.flatten)
+        .then(data => setRefId(data, options.targets[index].refId));
+    });
+
     // Data for panel (all targets)
-    return Promise.all(_.flatten(promises))
+    return Promise.all(targetPromises)
       .then(_.flatten)
       .then(data => {
         return { data: data };
@@ -180,7 +231,16 @@ export class ZabbixDatasource {
     return this.zabbix.getItemsFromTarget(target, getItemOptions)
     .then(items => {
       queryStart = new Date().getTime();
-      return this.queryNumericDataForItems(items, target, timeRange, useTrends, options);
+      let queryHistory = this.queryNumericDataForItems(items, target, timeRange, useTrends, options);
+      if (!target.options || !target.options.showItemErrors) {
+        return queryHistory;
+      }
+
+      // Add table with errors of not supported items
+      return queryHistory.then(timeseries => {
+        let itemErrors = responseHandler.handleItemErrors(items);
+        return itemErrors.rows.length ? timeseries.concat(itemErrors) : timeseries;
+      });
     }).then(result => {
       queryEnd = new Date().getTime();
       if (this.enableDebugLog) {
@@ -196,9 +256,16 @@ export class ZabbixDatasource {
   queryNumericDataForItems(items, target, timeRange, useTrends, options) {
     let getHistoryPromise;
     options.valueType = this.getTrendValueType(target);
-    options.consolidateBy = getConsolidateBy(target) || options.valueType;
-
-    if (useTrends) {
+    // Functions set in the query take precedence over datasource default
+    options.consolidateBy = getConsolidateBy(target) || getTrendValue(target) || this.defaultConsolidateBy || options.valueType;
+
+    let hostGroupAggregation = getHostGroupAggregation(target);
+    let weightItemFilter = getWeightItemFilter(target);
+    if (hostGroupAggregation) {
+      getHistoryPromise = this.queryHostGroupAggregatedData(items, target, timeRange, useTrends, options, hostGroupAggregation);
+    } else if (weightItemFilter) {
+      getHistoryPromise = this.queryWeightedAvgData(items, target, timeRange, useTrends, options, weightItemFilter);
+    } else if (useTrends) {
       getHistoryPromise = this.zabbix.getTrends(items, timeRange, options);
     } else {
       getHistoryPromise = this.zabbix.getHistoryTS(items, timeRange, options);
@@ -206,16 +273,97 @@ export class ZabbixDatasource {
 
     return getHistoryPromise
     .then(timeseries => this.applyDataProcessingFunctions(timeseries, target))
-    .then(timeseries => downsampleSeries(timeseries, options));
+    .then(timeseries => downsampleSeries(timeseries, options, target));
   }
 
-  getTrendValueType(target) {
-    // Find trendValue() function and get specified trend value
-    var trendFunctions = _.map(metricFunctions.getCategories()['Trends'], 'name');
-    var trendValueFunc = _.find(target.functions, func => {
-      return _.includes(trendFunctions, func.def.name);
+  /**
+   * Query history separately for each matched host group and aggregate items of the group
+   * into single series named by group.
+   */
+  queryHostGroupAggregatedData(items, target, timeRange, useTrends, options, aggFunc) {
+    return this.zabbix.getGroupsWithHosts(target.group.filter)
+    .then(groups => {
+      return Promise.all(_.map(groups, group => {
+        let groupItems = _.filter(items, item => _.includes(group.hostids, item.hostid));
+        if (!groupItems.length) {
+          return [];
+        }
+
+        let getGroupHistory = useTrends ?
+          this.zabbix.getTrends(groupItems, timeRange, options) :
+          this.zabbix.getHistoryTS(groupItems, timeRange, options);
+
+        return getGroupHistory.then(timeseries => {
+          let series = _.filter(_.map(timeseries, 'datapoints'), datapoints => datapoints.length);
+          if (!series.length) {
+            return [];
+          }
+          // Aggregate only values present at each timestamp, so gaps don't skew the result
+          let aggregateFunc = dataProcessor.aggregationFunctions[aggFunc] || dataProcessor.SUM;
+          let datapoints = dataProcessor.aggregateSeries(series, aggregateFunc);
+          return [{ target: group.name, datapoints: datapoints }];
+        });
+      }));
+    })
+    .then(_.flatten);
+  }
+
+  /**
+   * Calculate average of items weighted by last value of weight item from the same host,
+   * ie per-host CPU load weighted by number of cores. Items of hosts without weight are skipped.
+   */
+  queryWeightedAvgData(items, target, timeRange, useTrends, options, weightItemFilter) {
+    let weightTarget = Object.assign({}, target, {
+      application: { filter: '' },
+      item: { filter: this.replaceTemplateVars(weightItemFilter, options.scopedVars) }
+    });
+
+    return this.zabbix.getItemsFromTarget(weightTarget, { itemtype: 'num' })
+    .then(weightItems => {
+      if (!weightItems.length) {
+        return [];
+      }
+      return this.zabbix.getItemsLastValues(_.map(weightItems, 'itemid'));
+    })
+    .then(weightItems => {
+      let weights = getWeightsByHost(weightItems);
+      let weightedItems = _.filter(items, item => weights[item.hostid] > 0);
+      return Promise.all(_.map(_.groupBy(weightedItems, 'hostid'), (hostItems, hostid) => {
+        let getHostHistory = useTrends ?
+          this.zabbix.getTrends(hostItems, timeRange, options) :
+          this.zabbix.getHistoryTS(hostItems, timeRange, options);
+
+        return getHostHistory.then(timeseries => {
+          return _.map(timeseries, series => ({ datapoints: series.datapoints, weight: weights[hostid] }));
+        });
+      }));
+    })
+    .then(_.flatten)
+    .then(itemSeries => {
+      itemSeries = _.filter(itemSeries, series => series.datapoints.length);
+      if (!itemSeries.length) {
+        return [];
+      }
+      // Weight series have the same timestamps and gaps as values, so after aligning series
+      // each timestamp is divided only by weights of values present at that time.
+      let weightedValues = _.map(itemSeries, series => {
+        return _.map(series.datapoints, point => [point[0] === null ? null : point[0] * series.weight, point[1]]);
+      });
+      let weightValues = _.map(itemSeries, series => {
+        return _.map(series.datapoints, point => [point[0] === null ? null : series.weight, point[1]]);
+      });
+      let sum = dataProcessor.aggregateSeries(weightedValues, dataProcessor.SUM);
+      let totalWeight = dataProcessor.aggregateSeries(weightValues, dataProcessor.SUM);
+      let datapoints = _.map(sum, (point, i) => {
+        let weight = totalWeight[i][0];
+        return [point[0] === null || !weight ? null : point[0] / weight, point[1]];
+      });
+      return [{ target: `weightedAvg(${weightItemFilter})`, datapoints: datapoints }];
     });
-    return trendValueFunc ? trendValueFunc.params[0] : "avg";
+  }
+
+  getTrendValueType(target) {
+    return getTrendValue(target) || "avg";
   }
 
   applyDataProcessingFunctions(timeseries_data, target) {
@@ -245,10 +393,17 @@ export class ZabbixDatasource {
         return _.includes(aggFuncNames, func.def.name);
       });
 
-      timeseries_data = [{
+      let aggregatedSeries = {
         target: lastAgg.text,
         datapoints: dp
-      }];
+      };
+
+      // Member series are dropped by default, so query returns single series (useful for alerting)
+      if (target.options && target.options.keepAggregatedSeries) {
+        timeseries_data = timeseries_data.concat(aggregatedSeries);
+      } else {
+        timeseries_data = [aggregatedSeries];
+      }
     }
 
     // Apply alias functions
@@ -284,6 +439,13 @@ export class ZabbixDatasource {
     return this.zabbix.getItemsFromTarget(target, options)
     .then(items => {
       return this.zabbix.getHistoryText(items, timeRange, target);
+    })
+    .then(result => {
+      // Functions like jsonField() can convert text history into numeric series
+      if (target.resultFormat === 'table' || !target.functions || !target.functions.length) {
+        return result;
+      }
+      return this.applyDataProcessingFunctions(result, target);
     });
   }
 
@@ -338,10 +500,12 @@ export class ZabbixDatasource {
       if (hosts.length) {
         let hostids = _.map(hosts, 'hostid');
         let appids = _.map(apps, 'applicationid');
+        let countBySeverity = target.triggers.countBySeverity;
         let options = {
           minSeverity: target.triggers.minSeverity,
           acknowledged: target.triggers.acknowledged,
-          count: target.triggers.count,
+          // Triggers list is required to split count by severity
+          count: target.triggers.count && !countBySeverity,
           timeFrom: timeFrom,
           timeTo: timeTo
         };
@@ -351,6 +515,10 @@ export class ZabbixDatasource {
           this.zabbix.getGroups(groupFilter)
         ])
         .then(([triggers, groups]) => {
+          if (countBySeverity) {
+            let byGroup = target.triggers.countByGroup;
+            return responseHandler.handleTriggersCountBySeverity(triggers, groups, timeRange, byGroup);
+          }
           return responseHandler.handleTriggersResponse(triggers, groups, timeRange);
         });
       } else {
@@ -359,6 +527,22 @@ export class ZabbixDatasource {
     });
   }
 
+  /**
+   * Query target data for Proxies mode
+   */
+  queryProxiesData(target) {
+    let proxyFilter = target.proxy ? target.proxy.filter : '';
+    return this.zabbix.getProxiesStatus(proxyFilter)
+    .then(proxies => responseHandler.handleProxiesStatus(proxies));
+  }
+
+  queryHostGroupsData(target) {
+    let groupFilter = target.group ? target.group.filter : '';
+    let minSeverity = target.triggers ? target.triggers.minSeverity : 0;
+    return this.zabbix.getHostGroupsOverview(groupFilter, minSeverity)
+    .then(({ groups, problems }) => responseHandler.handleHostGroupsOverview(groups, problems));
+  }
+
   /**
    * Test connection to Zabbix API and external history DB.
    */
@@ -367,6 +551,10 @@ export class ZabbixDatasource {
     .then(result => {
       const { zabbixVersion, dbConnectorStatus } = result;
       let message = `Zabbix API version: ${zabbixVersion}`;
+      if (this.pluginVersion) {
+        let build = this.pluginBuild ? ` (${this.pluginBuild})` : '';
+        message = `Plugin version: ${this.pluginVersion}${build}, ${message}`;
+      }
       if (dbConnectorStatus) {
         message += `, DB connector type: ${dbConnectorStatus.dsType}`;
       }
@@ -483,6 +671,18 @@ export class ZabbixDatasource {
     var annotation = options.annotation;
     var showOkEvents = annotation.showOkEvents ? c.SHOW_ALL_EVENTS : c.SHOW_OK_EVENTS;
 
+    let getEventAnnotations = this.getEventAnnotations(annotation, timeFrom, timeTo, showOkEvents);
+    if (!annotation.showMaintenance) {
+      return getEventAnnotations;
+    }
+
+    let getMaintenanceAnnotations = this.getMaintenanceAnnotations(annotation, timeFrom, timeTo);
+    return Promise.all([getEventAnnotations, getMaintenanceAnnotations])
+    .then(results => _.flatten(results));
+  }
+
+  getEventAnnotations(annotation, timeFrom, timeTo, showOkEvents) {
+
     // Show all triggers
     let triggersOptions = {
       showTriggers: c.SHOW_ALL_TRIGGERS,
@@ -548,6 +748,39 @@ export class ZabbixDatasource {
     });
   }
 
+  /**
+   * Show maintenance periods of matched hosts as annotation regions.
+   */
+  getMaintenanceAnnotations(annotation, timeFrom, timeTo) {
+    let groupFilter = this.replaceTemplateVars(annotation.group, {});
+    let hostFilter = this.replaceTemplateVars(annotation.host, {});
+
+    return this.zabbix.getHosts(groupFilter, hostFilter)
+    .then(hosts => {
+      let hostids = _.map(hosts, 'hostid');
+      if (!hostids.length) {
+        return [];
+      }
+      return this.zabbix.getMaintenances(hostids, timeFrom, timeTo);
+    })
+    .then(maintenances => {
+      return _.flatMap(maintenances, maintenance => {
+        let tags = annotation.showHostname ? _.map(maintenance.hosts, 'name') : undefined;
+        return _.map(getMaintenancePeriods(maintenance, timeFrom, timeTo), period => {
+          return {
+            annotation: annotation,
+            time: period.from * 1000,
+            timeEnd: period.to * 1000,
+            isRegion: true,
+            title: 'Maintenance',
+            tags: tags,
+            text: maintenance.name + (maintenance.description ? `<br>${maintenance.description}` : '')
+          };
+        });
+      });
+    });
+  }
+
   /**
    * Get triggers and its details for panel's targets
    * Returns alert state ('ok' if no fired triggers, or 'alerting' if at least 1 trigger is fired)
@@ -602,7 +835,7 @@ export class ZabbixDatasource {
 
   // Replace template variables
   replaceTargetVariables(target, options) {
-    let parts = ['group', 'host', 'application', 'item'];
+    let parts = ['group', 'host', 'template', 'application', 'itemTag', 'item', 'proxy'];
     _.forEach(parts, p => {
       if (target[p] && target[p].filter) {
         target[p].filter = this.replaceTemplateVars(target[p].filter, options.scopedVars);
@@ -645,6 +878,65 @@ function bindFunctionDefs(functionDefs, category) {
   });
 }
 
+/**
+ * Get maintenance periods within given time range. One time periods are shown as is, recurring
+ * periods are expanded into separate occurrences within maintenance active window.
+ */
+function getMaintenancePeriods(maintenance, timeFrom, timeTo) {
+  let activeSince = Number(maintenance.active_since);
+  let activeTill = Number(maintenance.active_till);
+  let rangeFrom = Math.max(timeFrom, activeSince);
+  let rangeTo = Math.min(timeTo, activeTill);
+  let periods = _.flatMap(maintenance.timeperiods, timeperiod => {
+    if (timeperiod.timeperiod_type === c.ZBX_MAINTENANCE_ONE_TIME) {
+      let start = Number(timeperiod.start_date);
+      return [{ from: start, to: start + Number(timeperiod.period) }];
+    }
+    return utils.getMaintenanceOccurrences(timeperiod, activeSince, rangeFrom, rangeTo);
+  });
+
+  return _.sortBy(_.uniqWith(periods, _.isEqual), 'from')
+  .filter(period => period.from <= rangeTo && period.to >= rangeFrom)
+  .map(period => {
+    return {
+      from: Math.max(period.from, rangeFrom),
+      to: Math.min(period.to, rangeTo)
+    };
+  });
+}
+
+/**
+ * Build headers carrying current Grafana org id and user login, so reverse proxy
+ * in front of Zabbix could route requests by tenant. Values are taken from browser
+ * and can be spoofed by user, so headers are advisory only and must not be trusted
+ * for access control. Trusted X-Grafana-User header is added by Grafana server
+ * itself if [dataproxy] send_user_header option is enabled.
+ */
+function getContextHeaders(orgIdHeader, userLoginHeader) {
+  let headers = {};
+  let user = config.bootData && config.bootData.user;
+  if (!user) {
+    return headers;
+  }
+
+  if (orgIdHeader && user.orgId) {
+    headers[orgIdHeader] = String(user.orgId);
+  }
+  if (userLoginHeader && user.login) {
+    headers[userLoginHeader] = user.login;
+  }
+  return headers;
+}
+
+function getTrendValue(target) {
+  // Find trendValue() function and get specified trend value
+  const trendFunctions = _.map(metricFunctions.getCategories()['Trends'], 'name');
+  const trendValueFunc = _.find(target.functions, func => {
+    return _.includes(trendFunctions, func.def.name);
+  });
+  return trendValueFunc ? trendValueFunc.params[0] : undefined;
+}
+
 function getConsolidateBy(target) {
   let consolidateBy;
   let funcDef = _.find(target.functions, func => {
@@ -656,13 +948,57 @@ function getConsolidateBy(target) {
   return consolidateBy;
 }
 
-function downsampleSeries(timeseries_data, options) {
+function getHostGroupAggregation(target) {
+  let funcDef = _.find(target.functions, func => {
+    return func.def.name === 'aggregateByHostGroup';
+  });
+  return funcDef && funcDef.params.length ? funcDef.params[0] : null;
+}
+
+function setRefId(data, refId) {
+  if (refId) {
+    _.forEach(data, result => {
+      if (result && result.refId === undefined) {
+        result.refId = refId;
+      }
+    });
+  }
+  return data;
+}
+
+function getWeightItemFilter(target) {
+  let funcDef = _.find(target.functions, func => {
+    return func.def.name === 'weightedAvg';
+  });
+  return funcDef && funcDef.params.length ? funcDef.params[0] : null;
+}
+
+/**
+ * Build map of host weights from last values of weight items. First weight item of the host is used.
+ */
+function getWeightsByHost(weightItems) {
+  let weights = {};
+  _.forEach(weightItems, item => {
+    let weight = Number(item.lastvalue);
+    if (weights[item.hostid] === undefined && !isNaN(weight) && item.lastvalue !== '') {
+      weights[item.hostid] = weight;
+    }
+  });
+  return weights;
+}
+
+function downsampleSeries(timeseries_data, options, target) {
   let defaultAgg = dataProcessor.aggregationFunctions['avg'];
   let consolidateByFunc = dataProcessor.aggregationFunctions[options.consolidateBy] || defaultAgg;
+  let downsampling = target && target.options ? target.options.downsampling : c.DOWNSAMPLING_CONSOLIDATE;
   return _.map(timeseries_data, timeseries => {
     if (timeseries.datapoints.length > options.maxDataPoints) {
-      timeseries.datapoints = dataProcessor
-        .groupBy(options.interval, consolidateByFunc, timeseries.datapoints);
+      if (downsampling === c.DOWNSAMPLING_LTTB) {
+        timeseries.datapoints = dataProcessor.downsampleLTTB(timeseries.datapoints, options.maxDataPoints);
+      } else {
+        timeseries.datapoints = dataProcessor
+          .groupBy(options.interval, consolidateByFunc, timeseries.datapoints);
+      }
     }
     return timeseries;
   });
@@ -112,6 +112,24 @@ addFuncDef({
   defaultParams: [0],
 });
 
+addFuncDef({
+  name: 'jsonField',
+  category: 'Transform',
+  params: [
+    {name: 'path', type: 'string'}
+  ],
+  defaultParams: ['value'],
+});
+
+addFuncDef({
+  name: 'mask',
+  category: 'Transform',
+  params: [
+    {name: 'mask', type: 'int', options: [1, 255, 65535]}
+  ],
+  defaultParams: [255],
+});
+
 // Aggregate
 
 addFuncDef({
@@ -226,6 +244,55 @@ addFuncDef({
   defaultParams: ['asc']
 });
 
+addFuncDef({
+  name: 'sortByValue',
+  category: 'Filter',
+  params: [
+    { name: 'value', type: 'string', options: ['avg', 'min', 'max', 'sum', 'count', 'median'] },
+    { name: 'direction', type: 'string', options: ['asc', 'desc'] }
+  ],
+  defaultParams: ['avg', 'desc']
+});
+
+addFuncDef({
+  name: 'extractLabel',
+  category: 'Filter',
+  params: [
+    { name: 'field', type: 'string', options: ['item', 'key', 'host'] },
+    { name: 'regex', type: 'string' },
+    { name: 'label', type: 'string' }
+  ],
+  defaultParams: ['key', '\\[(.*)\\]', 'param']
+});
+
+addFuncDef({
+  name: 'groupByLabel',
+  category: 'Filter',
+  params: [
+    { name: 'label', type: 'string', options: ['host'] },
+    { name: 'function', type: 'string', options: ['avg', 'min', 'max', 'sum', 'count', 'median'] }
+  ],
+  defaultParams: ['host', 'avg']
+});
+
+addFuncDef({
+  name: 'anomalyScore',
+  category: 'Filter',
+  params: [
+    { name: 'windowSize', type: 'int', options: [10, 60, 100, 600] }
+  ],
+  defaultParams: [60],
+});
+
+addFuncDef({
+  name: 'bit',
+  category: 'Filter',
+  params: [
+    { name: 'bits', type: 'string', options: ['0', '0,1,2,3'] }
+  ],
+  defaultParams: ['0']
+});
+
 // Trends
 
 addFuncDef({
@@ -288,6 +355,24 @@ addFuncDef({
   defaultParams: ['avg'],
 });
 
+addFuncDef({
+  name: 'aggregateByHostGroup',
+  category: 'Special',
+  params: [
+    { name: 'function', type: 'string', options: ['sum', 'avg'] }
+  ],
+  defaultParams: ['sum'],
+});
+
+addFuncDef({
+  name: 'weightedAvg',
+  category: 'Special',
+  params: [
+    { name: 'weightItem', type: 'string' }
+  ],
+  defaultParams: ['/CPU cores/'],
+});
+
 _.each(categories, function(funcList, catName) {
   categories[catName] = _.sortBy(funcList, 'name');
 });
@@ -21,7 +21,9 @@ export class ZabbixQueryController extends QueryCtrl {
       {value: 'text',      text: 'Text',        mode: c.MODE_TEXT},
       {value: 'itservice', text: 'IT Services', mode: c.MODE_ITSERVICE},
       {value: 'itemid',    text: 'Item ID',     mode: c.MODE_ITEMID},
-      {value: 'triggers',  text: 'Triggers',    mode: c.MODE_TRIGGERS}
+      {value: 'triggers',  text: 'Triggers',    mode: c.MODE_TRIGGERS},
+      {value: 'proxies',   text: 'Proxies',     mode: c.MODE_PROXIES},
+      {value: 'hostgroups', text: 'Host groups', mode: c.MODE_HOST_GROUPS}
     ];
 
     this.$scope.editorMode = {
@@ -29,7 +31,9 @@ export class ZabbixQueryController extends QueryCtrl {
       TEXT: c.MODE_TEXT,
       ITSERVICE: c.MODE_ITSERVICE,
       ITEMID: c.MODE_ITEMID,
-      TRIGGERS: c.MODE_TRIGGERS
+      TRIGGERS: c.MODE_TRIGGERS,
+      PROXIES: c.MODE_PROXIES,
+      HOST_GROUPS: c.MODE_HOST_GROUPS
     };
 
     this.slaPropertyList = [
@@ -48,14 +52,21 @@ export class ZabbixQueryController extends QueryCtrl {
 
     this.resultFormats = [{ text: 'Time series', value: 'time_series' }, { text: 'Table', value: 'table' }];
 
+    this.downsamplingMethods = [
+      { text: 'Consolidate', value: c.DOWNSAMPLING_CONSOLIDATE },
+      { text: 'LTTB', value: c.DOWNSAMPLING_LTTB }
+    ];
+
     this.triggerSeverity = c.TRIGGER_SEVERITY;
 
     // Map functions for bs-typeahead
     this.getGroupNames = _.bind(this.getMetricNames, this, 'groupList');
     this.getHostNames = _.bind(this.getMetricNames, this, 'hostList', true);
+    this.getTemplateNames = _.bind(this.getMetricNames, this, 'templateList');
     this.getApplicationNames = _.bind(this.getMetricNames, this, 'appList');
     this.getItemNames = _.bind(this.getMetricNames, this, 'itemList');
     this.getITServices = _.bind(this.getMetricNames, this, 'itServiceList');
+    this.getProxyNames = _.bind(this.getMetricNames, this, 'proxyList');
     this.getVariables = _.bind(this.getTemplateVariables, this);
 
     // Update metric suggestion when template variable was changed
@@ -84,17 +95,27 @@ export class ZabbixQueryController extends QueryCtrl {
         'mode': c.MODE_METRICS,
         'group': { 'filter': "" },
         'host': { 'filter': "" },
+        'template': { 'filter': "" },
         'application': { 'filter': "" },
+        'itemTag': { 'filter': "" },
         'item': { 'filter': "" },
+        'proxy': { 'filter': "" },
         'functions': [],
         'triggers': {
           'count': true,
-          'minSeverity': 3,
+          'countBySeverity': false,
+          'countByGroup': false,
+          'minSeverity': getDefaultMinSeverity(this.datasource),
           'acknowledged': 2
         },
         'options': {
           'showDisabledItems': false,
-          'skipEmptyValues': false
+          'skipEmptyValues': false,
+          'showItemErrors': false,
+          'dedupItems': false,
+          'keepAggregatedSeries': false,
+          'skipMaintenanceHosts': false,
+          'downsampling': c.DOWNSAMPLING_CONSOLIDATE
         },
         'table': {
           'skipEmptyValues': false
@@ -116,6 +137,12 @@ export class ZabbixQueryController extends QueryCtrl {
         _.defaults(target, {slaProperty: {name: "SLA", property: "sla"}});
         this.suggestITServices();
       }
+      else if (target.mode === c.MODE_PROXIES) {
+        this.suggestProxies();
+      }
+      else if (target.mode === c.MODE_HOST_GROUPS) {
+        this.suggestGroups();
+      }
     };
 
     this.init();
@@ -128,6 +155,7 @@ export class ZabbixQueryController extends QueryCtrl {
     return Promise.all([
       this.suggestGroups(),
       this.suggestHosts(),
+      this.suggestTemplates(),
       this.suggestApps(),
       this.suggestItems(itemtype)
     ]);
@@ -172,6 +200,14 @@ export class ZabbixQueryController extends QueryCtrl {
     });
   }
 
+  suggestTemplates() {
+    return this.zabbix.getAllTemplates()
+    .then(templates => {
+      this.metric.templateList = templates;
+      return templates;
+    });
+  }
+
   suggestApps() {
     let groupFilter = this.replaceTemplateVars(this.target.group.filter);
     let hostFilter = this.replaceTemplateVars(this.target.host.filter);
@@ -188,7 +224,8 @@ export class ZabbixQueryController extends QueryCtrl {
     let appFilter = this.replaceTemplateVars(this.target.application.filter);
     let options = {
       itemtype: itemtype,
-      showDisabledItems: this.target.options.showDisabledItems
+      showDisabledItems: this.target.options.showDisabledItems,
+      itemTagFilter: this.replaceTemplateVars(this.target.itemTag.filter)
     };
 
     return this.zabbix
@@ -207,6 +244,14 @@ export class ZabbixQueryController extends QueryCtrl {
     });
   }
 
+  suggestProxies() {
+    return this.zabbix.getFilteredProxies('/.*/')
+    .then(proxies => {
+      this.metric.proxyList = proxies;
+      return proxies;
+    });
+  }
+
   isRegex(str) {
     return utils.isRegex(str);
   }
@@ -233,7 +278,7 @@ export class ZabbixQueryController extends QueryCtrl {
    * Check query for template variables
    */
   isContainsVariables() {
-    return _.some(['group', 'host', 'application'], field => {
+    return _.some(['group', 'host', 'template', 'application', 'itemTag'], field => {
       if (this.target[field] && this.target[field].filter) {
         return utils.isTemplateVariable(this.target[field].filter, this.templateSrv.variables);
       } else {
@@ -304,11 +349,17 @@ export class ZabbixQueryController extends QueryCtrl {
   renderQueryOptionsText() {
     var optionsMap = {
       showDisabledItems: "Show disabled items",
-      skipEmptyValues: "Skip empty values"
+      skipEmptyValues: "Skip empty values",
+      showItemErrors: "Show item errors",
+      dedupItems: "Deduplicate items",
+      keepAggregatedSeries: "Keep aggregated series",
+      skipMaintenanceHosts: "Skip hosts in maintenance",
+      downsampling: "Downsampling"
     };
     var options = [];
     _.forOwn(this.target.options, (value, key) => {
-      if (value) {
+      // Skip options removed from editor but still saved in old targets
+      if (value && optionsMap[key]) {
         if (value === true) {
           // Show only option name (if enabled) for boolean options
           options.push(optionsMap[key]);
@@ -334,3 +385,8 @@ export class ZabbixQueryController extends QueryCtrl {
     this.targetChanged();
   }
 }
+
+function getDefaultMinSeverity(datasource) {
+  const minSeverity = datasource.defaultMinSeverity;
+  return minSeverity !== undefined && minSeverity !== null ? minSeverity : c.SEV_AVERAGE;
+}
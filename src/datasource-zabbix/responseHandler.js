@@ -1,6 +1,7 @@
 import _ from 'lodash';
 import TableModel from 'grafana/app/core/table_model';
 import * as c from './constants';
+import * as utils from './utils';
 
 /**
  * Convert Zabbix API history.get response to Grafana format
@@ -26,18 +27,47 @@ function convertHistory(history, items, addHostName, convertPointCallback) {
   var grouped_history = _.groupBy(history, 'itemid');
   var hosts = _.uniqBy(_.flatten(_.map(items, 'hosts')), 'hostid');  //uniqBy is needed to deduplicate
 
-  return _.map(grouped_history, function(hist, itemid) {
-    var item = _.find(items, {'itemid': itemid});
+  // Build indexes once instead of searching item and host for each series
+  var itemsIndex = _.keyBy(items, 'itemid');
+  var hostsIndex = _.keyBy(hosts, 'hostid');
+
+  // History may contain points of items which weren't requested (web items, permission changes),
+  // skip them instead of failing whole query
+  var unknownItemIds = _.filter(_.keys(grouped_history), itemid => !itemsIndex[itemid]);
+  if (unknownItemIds.length) {
+    var skippedCount = _.sumBy(unknownItemIds, itemid => grouped_history[itemid].length);
+    console.warn(`Zabbix: skipped ${skippedCount} history points of unknown items`, unknownItemIds);
+    grouped_history = _.omit(grouped_history, unknownItemIds);
+  }
+
+  var timeseries = _.map(grouped_history, function(hist, itemid) {
+    var item = itemsIndex[itemid];
+    var host = hostsIndex[item.hostid];
     var alias = item.name;
-    if (_.keys(hosts).length > 1 && addHostName) {   //only when actual multi hosts selected
-      var host = _.find(hosts, {'hostid': item.hostid});
+    if (hosts.length > 1 && addHostName && host) {   //only when actual multi hosts selected
       alias = host.name + ": " + alias;
     }
-    return {
+    var series = {
       target: alias,
-      datapoints: _.map(hist, convertPointCallback)
+      datapoints: utils.normalizeDatapoints(_.map(hist, convertPointCallback)),
+      tags: utils.getSeriesTags(item, host)
     };
+    // Grafana 6 can't show notices for legacy series, so warn in console like other compatibility problems
+    if (item.value_type === c.ZBX_VALUE_TYPE_UINT64 && isPrecisionReduced(series.datapoints)) {
+      console.warn(`Zabbix: values of item "${item.name}" exceed ${Number.MAX_SAFE_INTEGER}, precision is reduced`);
+    }
+    return series;
   });
+
+  // Keep legend order stable between refreshes
+  return _.sortBy(timeseries, [series => series.tags.host, series => series.tags.item]);
+}
+
+/**
+ * Unsigned 64-bit values greater than 2^53 - 1 can't be represented by JS number exactly.
+ */
+function isPrecisionReduced(datapoints) {
+  return _.some(datapoints, point => Math.abs(point[c.DATAPOINT_VALUE]) > Number.MAX_SAFE_INTEGER);
 }
 
 function sortTimeseries(timeseries) {
@@ -95,6 +125,28 @@ function handleHistoryAsTable(history, items, target) {
   return table;
 }
 
+/**
+ * Build table with error messages of not supported items.
+ */
+function handleItemErrors(items) {
+  let table = new TableModel();
+  table.addColumn({text: 'Host'});
+  table.addColumn({text: 'Item'});
+  table.addColumn({text: 'Key'});
+  table.addColumn({text: 'Error'});
+
+  _.each(items, item => {
+    if (item.state !== c.ZBX_ITEM_STATE_NOT_SUPPORTED) {
+      return;
+    }
+    let host = _.first(item.hosts);
+    host = host ? host.name : "";
+    table.rows.push([host, item.name, item.key_, item.error]);
+  });
+
+  return table;
+}
+
 function convertText(target, point) {
   let value = point.value;
 
@@ -110,7 +162,7 @@ function convertText(target, point) {
 }
 
 function extractText(str, pattern, useCaptureGroups) {
-  let extractPattern = new RegExp(pattern);
+  let extractPattern = utils.compileRegex(pattern);
   let extractedValue = extractPattern.exec(str);
   if (extractedValue) {
     if (useCaptureGroups) {
@@ -181,6 +233,78 @@ function handleTriggersResponse(triggers, groups, timeRange) {
   }
 }
 
+/**
+ * Convert triggers to problem counts by severity (one series per severity), suitable for
+ * Singlestat and Bar Gauge panels. If byGroup is set, counts are split by host group.
+ */
+function handleTriggersCountBySeverity(triggers, groups, timeRange, byGroup) {
+  const timestamp = timeRange[1] * 1000;
+  const severities = _.orderBy(c.TRIGGER_SEVERITY, ['val'], ['desc']);
+  const buildSeries = (name, severityStats) => {
+    return _.map(severities, severity => {
+      return {
+        target: name ? `${name}: ${severity.text}` : severity.text,
+        datapoints: [[severityStats[severity.val], timestamp]]
+      };
+    });
+  };
+
+  if (!byGroup) {
+    let stats = _.countBy(triggers, 'priority');
+    _.each(severities, severity => {
+      stats[severity.val] = stats[severity.val] || 0;
+    });
+    return buildSeries(null, stats);
+  }
+
+  const stats = getTriggerStats(triggers);
+  const groupNames = _.map(groups, 'name');
+  return _.flatten(_.map(groupNames, group => {
+    return stats[group] ? buildSeries(group, stats[group]) : [];
+  }));
+}
+
+const PROXY_MODES = {
+  [c.ZBX_PROXY_ACTIVE]: 'active',
+  [c.ZBX_PROXY_PASSIVE]: 'passive'
+};
+
+function handleProxiesStatus(proxies) {
+  let table = new TableModel();
+  table.addColumn({text: 'Proxy'});
+  table.addColumn({text: 'Mode'});
+  table.addColumn({text: 'Last seen', type: 'time'});
+  table.addColumn({text: 'Hosts'});
+
+  _.each(proxies, proxy => {
+    let mode = PROXY_MODES[proxy.status] || proxy.status;
+    let lastSeen = Number(proxy.lastaccess) ? Number(proxy.lastaccess) * 1000 : null;
+    let hostsCount = proxy.hosts ? proxy.hosts.length : 0;
+    table.rows.push([proxy.host, mode, lastSeen, hostsCount]);
+  });
+
+  return table;
+}
+
+function handleHostGroupsOverview(groups, problems) {
+  let table = new TableModel();
+  table.addColumn({text: 'Group'});
+  table.addColumn({text: 'Hosts'});
+  table.addColumn({text: 'Enabled'});
+  table.addColumn({text: 'Disabled'});
+  table.addColumn({text: 'Problems'});
+
+  let problemsCount = _.countBy(_.flatMap(problems, trigger => _.map(trigger.groups, 'groupid')));
+
+  _.each(groups, group => {
+    let hosts = group.hosts || [];
+    let enabled = _.filter(hosts, { status: c.ZBX_HOST_MONITORED }).length;
+    table.rows.push([group.name, hosts.length, enabled, hosts.length - enabled, problemsCount[group.groupid] || 0]);
+  });
+
+  return table;
+}
+
 function getTriggerStats(triggers) {
   let groups = _.uniq(_.flattenDeep(_.map(triggers, (trigger) => _.map(trigger.groups, 'name'))));
   // let severity = _.map(c.TRIGGER_SEVERITY, 'text');
@@ -238,8 +362,12 @@ export default {
   handleTrends,
   handleText,
   handleHistoryAsTable,
+  handleItemErrors,
   handleSLAResponse,
   handleTriggersResponse,
+  handleTriggersCountBySeverity,
+  handleProxiesStatus,
+  handleHostGroupsOverview,
   sortTimeseries
 };
 
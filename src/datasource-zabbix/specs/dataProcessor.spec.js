@@ -48,4 +48,117 @@ describe('dataProcessor', () => {
       ]);
     });
   });
+
+  describe('When apply jsonField() function', () => {
+    it('should extract numeric field from JSON values', () => {
+      let jsonField = dataProcessor.metricFunctions['jsonField'];
+      const dp = [
+        ['{"stats": {"active": 12}}', 1500000000000],
+        ['{"stats": {"active": "7.5"}}', 1500000001000],
+        ['{"stats": {}}', 1500000002000],
+        ['not a json', 1500000003000],
+        ['{"stats": {"active": {"value": 1}}}', 1500000004000],
+      ];
+      expect(jsonField('stats.active', dp)).toEqual([
+        [12, 1500000000000], [7.5, 1500000001000], [null, 1500000002000], [null, 1500000003000], [null, 1500000004000]
+      ]);
+      expect(jsonField('disks[1].usage', [['{"disks": [{"usage": 1}, {"usage": 2}]}', 1500000000000]])).toEqual([
+        [2, 1500000000000]
+      ]);
+    });
+  });
+
+  describe('When apply anomalyScore() function', () => {
+    it('should keep original series and add score series', () => {
+      let anomalyScore = dataProcessor.metricFunctions['anomalyScore'];
+      const datapoints = [[1, 1500000000000], [1, 1500000001000], [1, 1500000002000], [5, 1500000003000]];
+      const timeseries = [{ target: 'CPU load', datapoints, tags: { host: 'db01' } }];
+      const result = anomalyScore(3, timeseries);
+      expect(result.length).toBe(2);
+      expect(result[0]).toBe(timeseries[0]);
+      expect(result[1].target).toBe('CPU load anomaly');
+      expect(result[1].tags).toEqual({ host: 'db01', anomaly: 'true' });
+      expect(result[1].datapoints).toEqual([[0, 1500000003000]]);
+    });
+  });
+
+  describe('When apply bit() and mask() functions', () => {
+    it('should split series into bit flags', () => {
+      let bit = dataProcessor.metricFunctions['bit'];
+      const timeseries = [{ target: 'status', datapoints: [[5, 1500000000000], [2, 1500000001000], [null, 1500000002000]] }];
+      expect(bit('0, 1', timeseries)).toEqual([
+        { target: 'status bit 0', datapoints: [[1, 1500000000000], [0, 1500000001000], [null, 1500000002000]], tags: { bit: '0' } },
+        { target: 'status bit 1', datapoints: [[0, 1500000000000], [1, 1500000001000], [null, 1500000002000]], tags: { bit: '1' } },
+      ]);
+    });
+
+    it('should keep series tags and add bit label', () => {
+      let bit = dataProcessor.metricFunctions['bit'];
+      const timeseries = [{ target: 'status', datapoints: [[4, 1500000000000]], tags: { host: 'db01', item: 'status' } }];
+      expect(bit('2', timeseries)).toEqual([
+        { target: 'status bit 2', datapoints: [[1, 1500000000000]], tags: { host: 'db01', item: 'status', bit: '2' } },
+      ]);
+    });
+
+    it('should apply mask', () => {
+      let mask = dataProcessor.metricFunctions['mask'];
+      expect(mask(0xFF00, [[0x1234, 1500000000000], [null, 1500000001000]])).toEqual([
+        [0x1200, 1500000000000], [null, 1500000001000]
+      ]);
+    });
+  });
+
+  describe('When apply extractLabel() function', () => {
+    it('should add label from item key', () => {
+      let extractLabel = dataProcessor.metricFunctions['extractLabel'];
+      const timeseries = [
+        { target: 'Free space on /', datapoints: [], tags: { host: 'db01', item: 'Free space on /', key: 'vfs.fs.size[/,free]' } },
+        { target: 'Free space on /var', datapoints: [], tags: { host: 'db01', item: 'Free space on /var', key: 'vfs.fs.size[/var,free]' } },
+        { target: 'CPU load', datapoints: [], tags: { host: 'db01', item: 'CPU load', key: 'system.cpu.load' } },
+      ];
+      const result = extractLabel('key', '/\\[([^,]*),/', 'fs', timeseries);
+      expect(result.map(ts => ts.tags.fs)).toEqual(['/', '/var', undefined]);
+      expect(result[0].tags.host).toBe('db01');
+    });
+
+    it('should match each series from start when regex has global flag', () => {
+      let extractLabel = dataProcessor.metricFunctions['extractLabel'];
+      const timeseries = [
+        { target: 'a', datapoints: [], tags: { key: 'net.if.in[eth0]' } },
+        { target: 'b', datapoints: [], tags: { key: 'net.if.in[eth1]' } },
+      ];
+      const result = extractLabel('key', '/\\[(.*)\\]/g', 'iface', timeseries);
+      expect(result.map(ts => ts.tags.iface)).toEqual(['eth0', 'eth1']);
+    });
+  });
+
+  describe('When apply groupByLabel() function', () => {
+    it('should aggregate series with the same label', () => {
+      let groupByLabel = dataProcessor.metricFunctions['groupByLabel'];
+      const timeseries = [
+        { target: 'a', datapoints: [[1, 1000], [3, 3000]], tags: { host: 'db01' } },
+        { target: 'b', datapoints: [[4, 2000], [6, 3000]], tags: { host: 'db01' } },
+        { target: 'c', datapoints: [[10, 1000]], tags: { host: 'db02' } },
+        { target: 'd', datapoints: [[7, 1000]] },
+      ];
+      expect(groupByLabel('host', 'sum', timeseries)).toEqual([
+        { target: 'db01', datapoints: [[1, 1000], [6, 2000], [9, 3000]], tags: { host: 'db01' } },
+        { target: 'db02', datapoints: [[10, 1000]], tags: { host: 'db02' } },
+        { target: 'd', datapoints: [[7, 1000]] },
+      ]);
+    });
+  });
+
+  describe('When apply sortByValue() function', () => {
+    it('should sort series by aggregated value', () => {
+      let sortByValue = dataProcessor.metricFunctions['sortByValue'];
+      const timeseries = [
+        { target: 'a', datapoints: [[1, 1000], [5, 2000]] },
+        { target: 'b', datapoints: [[4, 1000], [4, 2000]] },
+        { target: 'c', datapoints: [[2, 1000], [2, 2000]] },
+      ];
+      expect(sortByValue('max', 'desc', timeseries).map(ts => ts.target)).toEqual(['a', 'b', 'c']);
+      expect(sortByValue('avg', 'asc', timeseries).map(ts => ts.target)).toEqual(['c', 'a', 'b']);
+    });
+  });
 });
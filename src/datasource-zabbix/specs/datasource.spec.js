@@ -2,6 +2,7 @@ import _ from 'lodash';
 import mocks from '../../test-setup/mocks';
 import { Datasource } from "../module";
 import { zabbixTemplateFormat } from "../datasource";
+import * as metricFunctions from "../metricFunctions";
 import { dateMath } from '@grafana/data';
 
 describe('ZabbixDatasource', () => {
@@ -142,6 +143,14 @@ describe('ZabbixDatasource', () => {
       });
     });
 
+    it('should set refId of the query', (done) => {
+      ctx.options.targets[0].refId = 'B';
+      ctx.ds.query(ctx.options).then(result => {
+        expect(result.data[0].refId).toBe('B');
+        done();
+      });
+    });
+
     it('should extract value if regex with capture group is used', (done) => {
       ctx.options.targets[0].textFilter = "Linux (.*)";
       ctx.ds.query(ctx.options).then(result => {
@@ -179,6 +188,210 @@ describe('ZabbixDatasource', () => {
     });
   });
 
+  describe('When applying aggregation functions', () => {
+    beforeEach(() => {
+      ctx.timeseries = [
+        { target: 'a', datapoints: [[1, 1500000000000], [2, 1500000001000]] },
+        { target: 'b', datapoints: [[3, 1500000000000], [4, 1500000001000]] },
+      ];
+      ctx.target = {
+        functions: [metricFunctions.createFuncInstance('sum', ['1s'])],
+        options: {}
+      };
+    });
+
+    it('should return only aggregated series by default', () => {
+      const result = ctx.ds.applyDataProcessingFunctions(ctx.timeseries, ctx.target);
+      expect(result).toEqual([
+        { target: 'sum(1s)', datapoints: [[4, 1500000000000], [6, 1500000001000]] }
+      ]);
+    });
+
+    it('should keep aggregated series if option is enabled', () => {
+      ctx.target.options.keepAggregatedSeries = true;
+      const result = ctx.ds.applyDataProcessingFunctions(ctx.timeseries, ctx.target);
+      expect(result.map(ts => ts.target)).toEqual(['a', 'b', 'sum(1s)']);
+    });
+  });
+
+  describe('When datasource default consolidateBy is set', () => {
+    beforeEach(() => {
+      ctx.ds.defaultConsolidateBy = 'max';
+      ctx.ds.zabbix.getTrends = jest.fn().mockResolvedValue([]);
+      ctx.items = [{ itemid: '1', hostid: '101', name: 'CPU load' }];
+      ctx.timeRange = [1500000000, 1500000100];
+    });
+
+    it('should use default if query has no consolidateBy() or trendValue()', (done) => {
+      const target = { functions: [], options: {} };
+      ctx.ds.queryNumericDataForItems(ctx.items, target, ctx.timeRange, true, {}).then(() => {
+        expect(ctx.ds.zabbix.getTrends.mock.calls[0][2].consolidateBy).toBe('max');
+        done();
+      });
+    });
+
+    it('should prefer trendValue() over default', (done) => {
+      const target = { functions: [metricFunctions.createFuncInstance('trendValue', ['min'])], options: {} };
+      ctx.ds.queryNumericDataForItems(ctx.items, target, ctx.timeRange, true, {}).then(() => {
+        expect(ctx.ds.zabbix.getTrends.mock.calls[0][2].consolidateBy).toBe('min');
+        done();
+      });
+    });
+
+    it('should prefer consolidateBy() over trendValue()', (done) => {
+      const target = {
+        functions: [
+          metricFunctions.createFuncInstance('trendValue', ['min']),
+          metricFunctions.createFuncInstance('consolidateBy', ['sum']),
+        ],
+        options: {}
+      };
+      ctx.ds.queryNumericDataForItems(ctx.items, target, ctx.timeRange, true, {}).then(() => {
+        expect(ctx.ds.zabbix.getTrends.mock.calls[0][2].consolidateBy).toBe('sum');
+        done();
+      });
+    });
+  });
+
+  describe('When querying maintenance annotations', () => {
+    const toUnix = (...args) => new Date(...args).getTime() / 1000;
+
+    beforeEach(() => {
+      ctx.ds.replaceTemplateVars = (str) => str;
+      ctx.ds.zabbix.getHosts = jest.fn().mockResolvedValue([{ hostid: '101', name: 'backend01' }]);
+      ctx.ds.zabbix.getMaintenances = jest.fn().mockResolvedValue([{
+        name: 'Nightly backup',
+        active_since: String(toUnix(2020, 0, 1)),
+        active_till: String(toUnix(2021, 0, 1)),
+        hosts: [{ hostid: '101', name: 'backend01' }],
+        timeperiods: [{ timeperiod_type: '2', every: '1', start_time: '7200', period: '3600' }]
+      }]);
+    });
+
+    it('should show each occurrence of recurring maintenance', (done) => {
+      const annotation = { group: '/.*/', host: 'backend01' };
+      ctx.ds.getMaintenanceAnnotations(annotation, toUnix(2020, 5, 1), toUnix(2020, 5, 3)).then(result => {
+        expect(result.map(a => [a.time, a.timeEnd])).toEqual([
+          [toUnix(2020, 5, 1, 2) * 1000, toUnix(2020, 5, 1, 3) * 1000],
+          [toUnix(2020, 5, 2, 2) * 1000, toUnix(2020, 5, 2, 3) * 1000],
+        ]);
+        done();
+      });
+    });
+  });
+
+  describe('When testing datasource', () => {
+    it('should show plugin version and build', (done) => {
+      ctx.ds.pluginVersion = '3.10.4';
+      ctx.ds.pluginBuild = 'abc1234';
+      ctx.ds.zabbix.testDataSource = jest.fn().mockResolvedValue({ zabbixVersion: '4.0.0' });
+      ctx.ds.testDatasource().then(result => {
+        expect(result.status).toBe('success');
+        expect(result.message).toBe('Plugin version: 3.10.4 (abc1234), Zabbix API version: 4.0.0');
+        done();
+      });
+    });
+  });
+
+  describe('When aggregating by host group', () => {
+    beforeEach(() => {
+      ctx.items = [
+        { itemid: '1', hostid: '101', name: 'CPU load' },
+        { itemid: '2', hostid: '102', name: 'CPU load' },
+        { itemid: '3', hostid: '103', name: 'CPU load' },
+      ];
+      ctx.ds.zabbix.getGroupsWithHosts = jest.fn().mockResolvedValue([
+        { name: 'Backend', groupid: '1', hostids: ['101', '102', '103'] },
+      ]);
+      ctx.ds.zabbix.getHistoryTS = jest.fn().mockResolvedValue([
+        { target: 'CPU load', datapoints: [[1, 1500000000000], [3, 1500000060000]] },
+        { target: 'CPU load', datapoints: [[5, 1500000060000]] },
+        { target: 'CPU load', datapoints: [] },
+      ]);
+      ctx.target = { group: { filter: 'Backend' } };
+    });
+
+    it('should average only values present at each timestamp', (done) => {
+      ctx.ds.queryHostGroupAggregatedData(ctx.items, ctx.target, [1500000000, 1500000100], false, {}, 'avg').then(result => {
+        expect(result).toEqual([
+          { target: 'Backend', datapoints: [[1, 1500000000000], [4, 1500000060000]] }
+        ]);
+        done();
+      });
+    });
+
+    it('should sum series without filling gaps by zeroes', (done) => {
+      ctx.ds.queryHostGroupAggregatedData(ctx.items, ctx.target, [1500000000, 1500000100], false, {}, 'sum').then(result => {
+        expect(result).toEqual([
+          { target: 'Backend', datapoints: [[1, 1500000000000], [8, 1500000060000]] }
+        ]);
+        done();
+      });
+    });
+  });
+
+  describe('When querying weighted average', () => {
+    beforeEach(() => {
+      ctx.ds.replaceTemplateVars = (str) => str;
+      ctx.items = [
+        { itemid: '1', hostid: '101', name: 'CPU load' },
+        { itemid: '2', hostid: '102', name: 'CPU load' },
+        { itemid: '3', hostid: '103', name: 'CPU load' },
+      ];
+      ctx.ds.zabbix.getItemsFromTarget = jest.fn().mockResolvedValue([
+        { itemid: '11', hostid: '101', name: 'CPU cores' },
+        { itemid: '12', hostid: '102', name: 'CPU cores' },
+      ]);
+      ctx.ds.zabbix.getItemsLastValues = jest.fn().mockResolvedValue([
+        { itemid: '11', hostid: '101', lastvalue: '2' },
+        { itemid: '12', hostid: '102', lastvalue: '6' },
+      ]);
+      ctx.ds.zabbix.getHistoryTS = jest.fn(items => {
+        const values = { '1': 1, '2': 3, '3': 100 };
+        return Promise.resolve(items.map(item => {
+          return { target: item.name, datapoints: [[values[item.itemid], 1500000000000]] };
+        }));
+      });
+    });
+
+    it('should weight items by value of weight item of the same host', (done) => {
+      const target = {
+        group: {filter: "/.*/"}, host: {filter: "/.*/"}, application: {filter: ""}, item: {filter: "CPU load"},
+        functions: [{ def: { name: 'weightedAvg' }, params: ['CPU cores'] }]
+      };
+      const options = { range: ctx.options.range, scopedVars: {} };
+      const timeRange = [1500000000, 1500000100];
+      ctx.ds.queryWeightedAvgData(ctx.items, target, timeRange, false, options, 'CPU cores').then(result => {
+        expect(ctx.ds.zabbix.getItemsFromTarget.mock.calls[0][0].item.filter).toBe('CPU cores');
+        expect(result).toEqual([
+          { target: 'weightedAvg(CPU cores)', datapoints: [[2.5, 1500000000000]] }
+        ]);
+        done();
+      });
+    });
+
+    it('should divide only by weights of values present at each timestamp', (done) => {
+      ctx.items[2].hostid = '101';
+      ctx.ds.zabbix.getHistoryTS = jest.fn(items => {
+        const datapoints = {
+          '1': [[1, 1500000000000], [1, 1500000060000]],
+          '2': [[3, 1500000060000]],
+          '3': [],
+        };
+        return Promise.resolve(items.map(item => ({ target: item.name, datapoints: datapoints[item.itemid] })));
+      });
+      const target = {
+        group: {filter: "/.*/"}, host: {filter: "/.*/"}, application: {filter: ""}, item: {filter: "CPU load"},
+        functions: [{ def: { name: 'weightedAvg' }, params: ['CPU cores'] }]
+      };
+      const options = { range: ctx.options.range, scopedVars: {} };
+      ctx.ds.queryWeightedAvgData(ctx.items, target, [1500000000, 1500000100], false, options, 'CPU cores').then(result => {
+        expect(result[0].datapoints).toEqual([[1, 1500000000000], [2.5, 1500000060000]]);
+        done();
+      });
+    });
+  });
+
   describe('When replacing template variables', () => {
 
     function testReplacingVariable(target, varValue, expectedResult, done) {
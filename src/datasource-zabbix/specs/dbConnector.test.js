@@ -44,4 +44,22 @@ describe('DBConnector', () => {
       return expect(dbConnector.loadDBDataSource()).rejects.toBe('Data Source with ID 45 not found');
     });
   });
+
+  describe('When converting DB response', () => {
+    const items = [{ itemid: '1', name: 'CPU load', key_: 'system.cpu.load', hostid: '101', hosts: [{ hostid: '101', name: 'db01' }] }];
+
+    it('should sort points and remove duplicate timestamps', () => {
+      const dbConnector = new DBConnector({ datasourceId: 42 }, datasourceSrv);
+      const history = [{ name: '1', points: [[2, 1500000060000], [1, 1500000000000], [3, 1500000060000]] }];
+      const result = dbConnector.handleGrafanaTSResponse(history, items);
+      expect(result[0].datapoints).toEqual([[1, 1500000000000], [3, 1500000060000]]);
+    });
+
+    it('should not change points shared by cached response', () => {
+      const dbConnector = new DBConnector({ datasourceId: 42 }, datasourceSrv);
+      const history = [{ name: '1', points: [[2, 1500000060000], [1, 1500000000000]] }];
+      dbConnector.handleGrafanaTSResponse(history, items);
+      expect(history[0].points).toEqual([[2, 1500000060000], [1, 1500000000000]]);
+    });
+  });
 });
@@ -0,0 +1,111 @@
+import fs from 'fs';
+import path from 'path';
+import responseHandler from '../responseHandler';
+
+/**
+ * Golden-file tests for the conversion layer. Each file in golden/responseHandler contains
+ * handler name, its arguments and expected output. Run tests with UPDATE_GOLDEN=1 environment
+ * variable to rewrite expected output after intended changes of response format.
+ */
+const GOLDEN_DIR = path.join(__dirname, 'golden', 'responseHandler');
+
+// Keep only meaningful fields of tables, internal fields of TableModel are not part of response format
+function serialize(result) {
+  return JSON.parse(JSON.stringify(result, (key, value) => {
+    if (value && value.type === 'table') {
+      return { type: value.type, columns: value.columns, rows: value.rows };
+    }
+    return value;
+  }));
+}
+
+describe('responseHandler golden files', () => {
+  const goldenFiles = fs.readdirSync(GOLDEN_DIR).filter(file => file.endsWith('.json'));
+
+  it('should have golden files', () => {
+    expect(goldenFiles.length).toBeGreaterThan(0);
+  });
+
+  goldenFiles.forEach(file => {
+    it(`should return expected output for ${file}`, () => {
+      const goldenPath = path.join(GOLDEN_DIR, file);
+      const golden = JSON.parse(fs.readFileSync(goldenPath, 'utf8'));
+      const result = serialize(responseHandler[golden.handler](...golden.args));
+
+      if (process.env.UPDATE_GOLDEN) {
+        golden.output = result;
+        fs.writeFileSync(goldenPath, JSON.stringify(golden, null, 2) + '\n');
+      }
+      expect(result).toEqual(golden.output);
+    });
+  });
+});
+
+describe('responseHandler', () => {
+  const items = [
+    { itemid: '1', name: 'CPU load', key_: 'system.cpu.load', value_type: '0', hostid: '101', hosts: [{ hostid: '101', name: 'db01' }] },
+  ];
+
+  describe('When history contains points of unknown items', () => {
+    beforeEach(() => {
+      jest.spyOn(console, 'warn').mockImplementation(() => {});
+    });
+
+    afterEach(() => {
+      console.warn.mockRestore();
+    });
+
+    const history = [
+      { itemid: '1', clock: '1500000000', ns: '0', value: '1.5' },
+      { itemid: '99', clock: '1500000000', ns: '0', value: '5' },
+      { itemid: '99', clock: '1500000060', ns: '0', value: '6' },
+    ];
+
+    it('should skip points of unknown items', () => {
+      const result = responseHandler.handleHistory(history, items);
+      expect(result.length).toBe(1);
+      expect(result[0].datapoints).toEqual([[1.5, 1500000000000]]);
+    });
+
+    it('should warn about skipped points', () => {
+      responseHandler.handleHistory(history, items);
+      expect(console.warn).toHaveBeenCalledWith('Zabbix: skipped 2 history points of unknown items', ['99']);
+    });
+
+    it('should not warn if all items are known', () => {
+      responseHandler.handleHistory(history.slice(0, 1), items);
+      expect(console.warn).not.toHaveBeenCalled();
+    });
+  });
+
+  describe('When history contains large unsigned values', () => {
+    const uintItems = [Object.assign({}, items[0], { value_type: '3' })];
+    const getHistory = value => [{ itemid: '1', clock: '1500000000', ns: '0', value }];
+
+    beforeEach(() => {
+      jest.spyOn(console, 'warn').mockImplementation(() => {});
+    });
+
+    afterEach(() => {
+      console.warn.mockRestore();
+    });
+
+    it('should not warn about values equal to MAX_SAFE_INTEGER', () => {
+      responseHandler.handleHistory(getHistory(String(Number.MAX_SAFE_INTEGER)), uintItems);
+      expect(console.warn).not.toHaveBeenCalled();
+    });
+
+    it('should warn about values greater than MAX_SAFE_INTEGER', () => {
+      const result = responseHandler.handleHistory(getHistory('9007199254740992'), uintItems);
+      expect(console.warn).toHaveBeenCalledWith(
+        'Zabbix: values of item "CPU load" exceed 9007199254740991, precision is reduced'
+      );
+      expect(result[0].meta).toBeUndefined();
+    });
+
+    it('should not warn about float items', () => {
+      responseHandler.handleHistory(getHistory('9007199254740992'), items);
+      expect(console.warn).not.toHaveBeenCalled();
+    });
+  });
+});
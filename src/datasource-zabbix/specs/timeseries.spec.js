@@ -41,4 +41,51 @@ describe('timeseries processing functions', () => {
       done();
     });
   });
+
+  describe('anomalyScore()', () => {
+    it('should calculate score against previous points', (done) => {
+      let points = [[1, 1], [3, 2], [1, 3], [3, 4], [2, 5], [7, 6]];
+
+      let result = ts.anomalyScore(points, 4);
+      expect(result.length).toBe(2);
+      expect(result[0]).toEqual([0, 5]);
+      expect(result[1][0]).toBeCloseTo(5.728, 3);
+      expect(result[1][1]).toBe(6);
+      done();
+    });
+
+    it('should return null for null values', (done) => {
+      let points = [[1, 1], [1, 2], [1, 3], [null, 4], [4, 5]];
+
+      let expected = [[null, 4], [0, 5]];
+
+      let result = ts.anomalyScore(points, 3);
+      expect(result).toEqual(expected);
+      done();
+    });
+  });
+
+  describe('downsampleLTTB()', () => {
+    it('should keep peaks of the series', (done) => {
+      let points = [];
+      for (let i = 0; i < 100; i++) {
+        points.push([i === 37 ? 100 : (i === 70 ? -50 : 1), i * 1000]);
+      }
+
+      let result = ts.downsampleLTTB(points, 10);
+      expect(result.length).toBe(10);
+      expect(result[0]).toEqual([1, 0]);
+      expect(result[9]).toEqual([1, 99000]);
+      expect(result).toContainEqual([100, 37000]);
+      expect(result).toContainEqual([-50, 70000]);
+      done();
+    });
+
+    it('should return points as is if threshold is not exceeded', (done) => {
+      let points = [[1, 1000], [null, 2000], [3, 3000]];
+
+      expect(ts.downsampleLTTB(points, 5)).toEqual([[1, 1000], [3, 3000]]);
+      done();
+    });
+  });
 });
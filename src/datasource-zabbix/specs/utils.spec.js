@@ -165,4 +165,174 @@ describe('Utils', () => {
       }
     });
   });
+
+  describe('matchInterface()', () => {
+    it('should parse interface filters', () => {
+      expect(utils.parseInterfaceFilter('ip:10.1.2.0/24')).toEqual({ field: 'ip', value: '10.1.2.0/24' });
+      expect(utils.parseInterfaceFilter('dns:*.prod.example.com')).toEqual({ field: 'dns', value: '*.prod.example.com' });
+      expect(utils.parseInterfaceFilter('backend01')).toBeNull();
+      expect(utils.parseInterfaceFilter('/^ip:.*/')).toBeNull();
+    });
+
+    it('should match ip by subnet', () => {
+      const filter = utils.parseInterfaceFilter('ip:10.1.2.0/24');
+      expect(utils.matchInterface({ ip: '10.1.2.15' }, filter)).toBe(true);
+      expect(utils.matchInterface({ ip: '10.1.3.15' }, filter)).toBe(false);
+      expect(utils.matchInterface({ ip: '' }, filter)).toBe(false);
+    });
+
+    it('should match dns by wildcard', () => {
+      const filter = utils.parseInterfaceFilter('dns:*.prod.example.com');
+      expect(utils.matchInterface({ dns: 'db01.prod.example.com' }, filter)).toBe(true);
+      expect(utils.matchInterface({ dns: 'db01.dev.example.com' }, filter)).toBe(false);
+      expect(utils.matchInterface({ dns: 'prodXexample.com' }, filter)).toBe(false);
+    });
+
+    it('should handle subnet edge cases', () => {
+      expect(utils.isIPInSubnet('192.168.1.1', '0.0.0.0/0')).toBe(true);
+      expect(utils.isIPInSubnet('255.255.255.255', '255.255.255.0/24')).toBe(true);
+      expect(utils.isIPInSubnet('10.0.0.1', '10.0.0.1/32')).toBe(true);
+      expect(utils.isIPInSubnet('10.0.0.2', '10.0.0.1/32')).toBe(false);
+      expect(utils.isIPInSubnet('10.0.0.256', '10.0.0.0/24')).toBe(false);
+      expect(utils.isIPInSubnet('10.0.0.1', '10.0.0.0/33')).toBe(false);
+    });
+  });
+
+  describe('buildRegex()', () => {
+    it('should reuse compiled regex for the same filter', () => {
+      const regex = utils.buildRegex('/^backend\\d+/i');
+      expect(regex.test('Backend01')).toBe(true);
+      expect(utils.buildRegex('/^backend\\d+/i')).toBe(regex);
+      expect(utils.buildRegex('/^backend\\d+/')).not.toBe(regex);
+    });
+
+    it('should not reuse regex with global flag', () => {
+      expect(utils.buildRegex('/backend/g')).not.toBe(utils.buildRegex('/backend/g'));
+    });
+  });
+
+  describe('parseItemTagFilter()', () => {
+    it('should parse tag conditions', () => {
+      expect(utils.parseItemTagFilter('component, !scope, env=~prod, team != db, service')).toEqual([
+        { tag: 'component', value: '', operator: 4 },
+        { tag: 'scope', value: '', operator: 5 },
+        { tag: 'env', value: 'prod', operator: 0 },
+        { tag: 'team', value: 'db', operator: 3 },
+        { tag: 'service', value: '', operator: 4 },
+      ]);
+      expect(utils.parseItemTagFilter('component=cpu')).toEqual([{ tag: 'component', value: 'cpu', operator: 1 }]);
+    });
+
+    it('should skip empty and invalid conditions', () => {
+      expect(utils.parseItemTagFilter('')).toEqual([]);
+      expect(utils.parseItemTagFilter('component=cpu,, !scope=host')).toEqual([
+        { tag: 'component', value: 'cpu', operator: 1 },
+      ]);
+    });
+  });
+
+  describe('getSeriesTags()', () => {
+    const item = { name: 'CPU load', key_: 'system.cpu.load' };
+
+    it('should add primary interface address', () => {
+      const host = {
+        name: 'backend01',
+        interfaces: [
+          { ip: '10.1.2.200', dns: '', main: '1', type: '2' },
+          { ip: '10.1.2.11', dns: 'backend01.example.com', main: '1', type: '1' },
+          { ip: '10.1.2.12', dns: '', main: '0', type: '1' },
+        ]
+      };
+      expect(utils.getSeriesTags(item, host)).toEqual({
+        host: 'backend01', item: 'CPU load', key: 'system.cpu.load', ip: '10.1.2.11', dns: 'backend01.example.com'
+      });
+    });
+
+    it('should add inventory fields', () => {
+      const host = { name: 'backend01', inventory: { location: 'DC1', os: '', host: 'other' } };
+      expect(utils.getSeriesTags(item, host)).toEqual({
+        host: 'backend01', item: 'CPU load', key: 'system.cpu.load', location: 'DC1'
+      });
+    });
+
+    it('should return basic tags if host has no interfaces', () => {
+      expect(utils.getSeriesTags(item, { name: 'backend01' })).toEqual({
+        host: 'backend01', item: 'CPU load', key: 'system.cpu.load'
+      });
+    });
+  });
+
+  describe('getMaintenanceOccurrences()', () => {
+    const toUnix = (...args) => new Date(...args).getTime() / 1000;
+    const activeSince = toUnix(2020, 0, 1);
+
+    it('should expand daily period', () => {
+      const timeperiod = { timeperiod_type: '2', every: '2', start_time: '3600', period: '7200' };
+      const occurrences = utils.getMaintenanceOccurrences(timeperiod, activeSince, toUnix(2020, 0, 1), toUnix(2020, 0, 6));
+      expect(occurrences).toEqual([
+        { from: toUnix(2020, 0, 1, 1), to: toUnix(2020, 0, 1, 3) },
+        { from: toUnix(2020, 0, 3, 1), to: toUnix(2020, 0, 3, 3) },
+        { from: toUnix(2020, 0, 5, 1), to: toUnix(2020, 0, 5, 3) },
+      ]);
+    });
+
+    it('should expand weekly period on selected days', () => {
+      // Monday and Wednesday, every 2 weeks
+      const timeperiod = { timeperiod_type: '3', every: '2', dayofweek: '5', start_time: '0', period: '3600' };
+      const occurrences = utils.getMaintenanceOccurrences(timeperiod, activeSince, toUnix(2020, 0, 1), toUnix(2020, 0, 20));
+      expect(_.map(occurrences, 'from')).toEqual([toUnix(2020, 0, 1), toUnix(2020, 0, 13), toUnix(2020, 0, 15)]);
+    });
+
+    it('should expand monthly period on day of month', () => {
+      // January and March
+      const timeperiod = { timeperiod_type: '4', month: '5', day: '15', start_time: '0', period: '3600' };
+      const occurrences = utils.getMaintenanceOccurrences(timeperiod, activeSince, toUnix(2020, 0, 1), toUnix(2020, 11, 31));
+      expect(_.map(occurrences, 'from')).toEqual([toUnix(2020, 0, 15), toUnix(2020, 2, 15)]);
+    });
+
+    it('should expand monthly period on last week day of month', () => {
+      // Last Friday of every month
+      const timeperiod = { timeperiod_type: '4', month: '4095', day: '0', every: '5', dayofweek: '16', start_time: '0', period: '3600' };
+      const occurrences = utils.getMaintenanceOccurrences(timeperiod, activeSince, toUnix(2020, 0, 1), toUnix(2020, 2, 1));
+      expect(_.map(occurrences, 'from')).toEqual([toUnix(2020, 0, 31), toUnix(2020, 1, 28)]);
+    });
+
+    it('should include occurrence started before time range', () => {
+      const timeperiod = { timeperiod_type: '2', every: '1', start_time: '79200', period: '14400' };
+      const occurrences = utils.getMaintenanceOccurrences(timeperiod, activeSince, toUnix(2020, 0, 3, 1), toUnix(2020, 0, 3, 2));
+      expect(occurrences).toEqual([{ from: toUnix(2020, 0, 2, 22), to: toUnix(2020, 0, 3, 2) }]);
+    });
+  });
+
+  describe('normalizeDatapoints()', () => {
+    it('should sort out of order points', () => {
+      const datapoints = [[3, 1500000120000], [1, 1500000000000], [2, 1500000060000]];
+      expect(utils.normalizeDatapoints(datapoints)).toEqual([
+        [1, 1500000000000], [2, 1500000060000], [3, 1500000120000]
+      ]);
+    });
+
+    it('should keep last value of points with duplicate timestamps', () => {
+      const datapoints = [[1, 1500000000000], [2, 1500000060000], [5, 1500000060000], [3, 1500000120000]];
+      expect(utils.normalizeDatapoints(datapoints)).toEqual([
+        [1, 1500000000000], [5, 1500000060000], [3, 1500000120000]
+      ]);
+    });
+
+    it('should return ordered points as is', () => {
+      const datapoints = [[1, 1500000000000], [2, 1500000060000]];
+      expect(utils.normalizeDatapoints(datapoints)).toBe(datapoints);
+    });
+  });
+
+  describe('compileRegex()', () => {
+    it('should return cached regex for the same pattern', () => {
+      expect(utils.compileRegex('cpu', 'i')).toBe(utils.compileRegex('cpu', 'i'));
+    });
+
+    it('should not cache stateful regex', () => {
+      expect(utils.compileRegex('cpu', 'g')).not.toBe(utils.compileRegex('cpu', 'g'));
+      expect(utils.compileRegex('cpu', 'y')).not.toBe(utils.compileRegex('cpu', 'y'));
+    });
+  });
 });
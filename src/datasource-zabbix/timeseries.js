@@ -67,6 +67,61 @@ function downsample(datapoints, time_to, ms_interval, func) {
   return downsampledSeries.reverse();
 }
 
+/**
+ * Downsample time series with Largest-Triangle-Three-Buckets algorithm. It keeps points
+ * which form visual shape of the series (peaks and drops), so it suits display-oriented panels.
+ * Points with null values are dropped.
+ * @param {number} threshold  number of points in result
+ */
+function downsampleLTTB(datapoints, threshold) {
+  const points = _.filter(datapoints, point => point[POINT_VALUE] !== null);
+  if (threshold >= points.length || threshold < 3) {
+    return points;
+  }
+
+  const sampled = [points[0]];
+  // First and last points are always kept, so split other points into threshold - 2 buckets
+  const bucketSize = (points.length - 2) / (threshold - 2);
+  let prevIndex = 0;
+
+  for (let i = 0; i < threshold - 2; i++) {
+    // Average point of the next bucket is the third vertex of the triangle
+    const nextStart = Math.floor((i + 1) * bucketSize) + 1;
+    const nextEnd = Math.min(Math.floor((i + 2) * bucketSize) + 1, points.length);
+    let avgTs = 0;
+    let avgValue = 0;
+    for (let j = nextStart; j < nextEnd; j++) {
+      avgTs += points[j][POINT_TIMESTAMP];
+      avgValue += points[j][POINT_VALUE];
+    }
+    avgTs /= nextEnd - nextStart;
+    avgValue /= nextEnd - nextStart;
+
+    // Pick point of current bucket forming the largest triangle with previously selected point
+    const prev = points[prevIndex];
+    const start = Math.floor(i * bucketSize) + 1;
+    const end = Math.floor((i + 1) * bucketSize) + 1;
+    let maxArea = -1;
+    let maxIndex = start;
+    for (let j = start; j < end; j++) {
+      const area = Math.abs(
+        (prev[POINT_TIMESTAMP] - avgTs) * (points[j][POINT_VALUE] - prev[POINT_VALUE]) -
+        (prev[POINT_TIMESTAMP] - points[j][POINT_TIMESTAMP]) * (avgValue - prev[POINT_VALUE])
+      );
+      if (area > maxArea) {
+        maxArea = area;
+        maxIndex = j;
+      }
+    }
+
+    sampled.push(points[maxIndex]);
+    prevIndex = maxIndex;
+  }
+
+  sampled.push(points[points.length - 1]);
+  return sampled;
+}
+
 /**
  * Group points by given time interval
  * datapoints: [[<value>, <unixtime>], ...]
@@ -189,6 +244,59 @@ function sumSeries(timeseries) {
   return sortByTime(new_timeseries);
 }
 
+/**
+ * Aggregate set of time series into one using given function (AVERAGE, MAX, etc).
+ * Series are aligned to common timestamps with linear interpolation, points out of
+ * series time range are ignored. Series should be sorted by time.
+ * @param {datapoints[]} timeseries array of time series
+ */
+function aggregateSeries(timeseries, aggFunc) {
+  let timestamps = _.sortBy(_.uniq(_.map(_.flatten(timeseries, true), point => point[POINT_TIMESTAMP])));
+  let alignedValues = _.map(timeseries, series => alignSeries(series, timestamps));
+
+  let new_timeseries = [];
+  for (let i = 0; i < timestamps.length; i++) {
+    let values = [];
+    for (let j = 0; j < alignedValues.length; j++) {
+      if (alignedValues[j][i] !== null) {
+        values.push(alignedValues[j][i]);
+      }
+    }
+    new_timeseries.push([values.length ? aggFunc(values) : null, timestamps[i]]);
+  }
+  return new_timeseries;
+}
+
+/**
+ * Get series values for given timestamps, missing values are interpolated.
+ */
+function alignSeries(series, timestamps) {
+  let values = [];
+  let j = 0;
+  for (let i = 0; i < timestamps.length; i++) {
+    let ts = timestamps[i];
+    while (j < series.length && series[j][POINT_TIMESTAMP] < ts) {
+      j++;
+    }
+
+    if (j < series.length && series[j][POINT_TIMESTAMP] === ts) {
+      values.push(series[j][POINT_VALUE]);
+    } else if (j === 0 || j >= series.length) {
+      values.push(null);
+    } else {
+      let left = series[j - 1];
+      let right = series[j];
+      if (left[POINT_VALUE] === null || right[POINT_VALUE] === null) {
+        values.push(null);
+      } else {
+        let ratio = (ts - left[POINT_TIMESTAMP]) / (right[POINT_TIMESTAMP] - left[POINT_TIMESTAMP]);
+        values.push(left[POINT_VALUE] + (right[POINT_VALUE] - left[POINT_VALUE]) * ratio);
+      }
+    }
+  }
+  return values;
+}
+
 function scale(datapoints, factor) {
   return _.map(datapoints, point => {
     return [
@@ -303,6 +411,47 @@ function simpleMovingAverage(datapoints, n) {
   return sma;
 }
 
+/**
+ * Calculates rolling z-score of each point: deviation from the mean of previous n points
+ * in standard deviations. Result starts from the (n+1)th point.
+ */
+function anomalyScore(datapoints, n) {
+  let scores = [];
+  let w_sum = 0;
+  let w_sum_sq = 0;
+  let w_count = 0;
+
+  const addValue = (value, sign) => {
+    if (value !== null) {
+      w_sum += sign * value;
+      w_sum_sq += sign * value * value;
+      w_count += sign;
+    }
+  };
+
+  // Initial window
+  for (let j = 0; j < n && j < datapoints.length; j++) {
+    addValue(datapoints[j][POINT_VALUE], 1);
+  }
+
+  for (let i = n; i < datapoints.length; i++) {
+    let value = datapoints[i][POINT_VALUE];
+    let score = null;
+    if (value !== null && w_count > 1) {
+      let mean = w_sum / w_count;
+      let variance = Math.max(w_sum_sq / w_count - mean * mean, 0);
+      let std = Math.sqrt(variance);
+      score = std > 0 ? (value - mean) / std : 0;
+    }
+    scores.push([score, datapoints[i][POINT_TIMESTAMP]]);
+
+    // Move window
+    addValue(value, 1);
+    addValue(datapoints[i - n][POINT_VALUE], -1);
+  }
+  return scores;
+}
+
 function expMovingAverage(datapoints, n) {
   let ema = [datapoints[0]];
   let ema_prev = datapoints[0][POINT_VALUE];
@@ -511,10 +660,12 @@ function flattenDatapoints(datapoints) {
 
 const exportedFunctions = {
   downsample,
+  downsampleLTTB,
   groupBy,
   groupBy_perf,
   groupByRange,
   sumSeries,
+  aggregateSeries,
   scale,
   offset,
   scale_perf,
@@ -522,6 +673,7 @@ const exportedFunctions = {
   rate,
   simpleMovingAverage,
   expMovingAverage,
+  anomalyScore,
   SUM,
   COUNT,
   AVERAGE,
@@ -33,6 +33,138 @@ export function expandItems(items) {
   return items;
 }
 
+/**
+ * Series tags are used by functions working with labels (extractLabel(), etc).
+ */
+export function getSeriesTags(item, host) {
+  let tags = {
+    host: host ? host.name : '',
+    item: item.name,
+    key: item.key_
+  };
+  if (isHostInMaintenance(host)) {
+    tags.maintenance = 'true';
+  }
+
+  let hostInterface = getPrimaryInterface(host && host.interfaces);
+  if (hostInterface) {
+    tags.ip = hostInterface.ip;
+    if (hostInterface.dns) {
+      tags.dns = hostInterface.dns;
+    }
+  }
+
+  // Inventory is returned as empty array if it's disabled for the host
+  _.forOwn(host && host.inventory, (value, field) => {
+    if (value && !tags[field]) {
+      tags[field] = value;
+    }
+  });
+  return tags;
+}
+
+/**
+ * Get default interface of the host, agent interface is preferred over SNMP, IPMI and JMX ones.
+ */
+export function getPrimaryInterface(interfaces) {
+  if (!interfaces || !interfaces.length) {
+    return null;
+  }
+  let defaultInterfaces = _.sortBy(_.filter(interfaces, { main: '1' }), i => Number(i.type));
+  return defaultInterfaces.length ? defaultInterfaces[0] : interfaces[0];
+}
+
+/**
+ * Sort points by time and remove points with duplicate timestamps (may appear after housekeeping),
+ * the last returned value is kept. Some panels and alert reducers expect ordered unique points.
+ */
+export function normalizeDatapoints(datapoints) {
+  let isNormalized = _.every(datapoints, (point, i) => {
+    return i === 0 || datapoints[i - 1][c.DATAPOINT_TS] < point[c.DATAPOINT_TS];
+  });
+  if (isNormalized) {
+    return datapoints;
+  }
+
+  let sorted = _.sortBy(datapoints, point => point[c.DATAPOINT_TS]);
+  return _.filter(sorted, (point, i) => {
+    return i === sorted.length - 1 || sorted[i + 1][c.DATAPOINT_TS] !== point[c.DATAPOINT_TS];
+  });
+}
+
+export function isHostInMaintenance(host) {
+  return !!host && host.maintenance_status === c.ZBX_HOST_MAINTENANCE_ON;
+}
+
+const DAY_MS = 24 * 60 * 60 * 1000;
+
+/**
+ * Expand recurring (daily, weekly or monthly) maintenance time period into occurrences
+ * overlapping given time range. Zabbix schedules periods in server time zone, browser
+ * time zone is used here since server one isn't available via API.
+ * @return {Array} list of {from, to} periods (unix time, seconds)
+ */
+export function getMaintenanceOccurrences(timeperiod, activeSince, timeFrom, timeTo) {
+  const period = Number(timeperiod.period) || 0;
+  const startTime = Number(timeperiod.start_time) || 0;
+  const firstDay = startOfDay(activeSince * 1000);
+  let occurrences = [];
+
+  // Occurrence started before the range could still last within it
+  let day = startOfDay(Math.max(timeFrom - period, activeSince) * 1000);
+  while (day.getTime() <= timeTo * 1000) {
+    if (isMaintenanceDay(timeperiod, day, firstDay)) {
+      let from = new Date(day.getFullYear(), day.getMonth(), day.getDate(), 0, 0, startTime).getTime() / 1000;
+      if (from <= timeTo && from + period >= timeFrom) {
+        occurrences.push({ from, to: from + period });
+      }
+    }
+    day = new Date(day.getFullYear(), day.getMonth(), day.getDate() + 1);
+  }
+  return occurrences;
+}
+
+function isMaintenanceDay(timeperiod, day, firstDay) {
+  const every = Number(timeperiod.every) || 1;
+  // Zabbix week starts on Monday (bit 0)
+  const weekDayBit = 1 << ((day.getDay() + 6) % 7);
+  const daysSinceFirst = Math.round((day.getTime() - firstDay.getTime()) / DAY_MS);
+
+  switch (timeperiod.timeperiod_type) {
+    case c.ZBX_MAINTENANCE_DAILY:
+      return daysSinceFirst % every === 0;
+    case c.ZBX_MAINTENANCE_WEEKLY: {
+      const firstWeekDay = (firstDay.getDay() + 6) % 7;
+      const weeksSinceFirst = Math.floor((daysSinceFirst + firstWeekDay) / 7);
+      return (Number(timeperiod.dayofweek) & weekDayBit) !== 0 && weeksSinceFirst % every === 0;
+    }
+    case c.ZBX_MAINTENANCE_MONTHLY: {
+      if ((Number(timeperiod.month) & (1 << day.getMonth())) === 0) {
+        return false;
+      }
+      const monthDay = Number(timeperiod.day) || 0;
+      if (monthDay) {
+        return day.getDate() === monthDay;
+      }
+      if ((Number(timeperiod.dayofweek) & weekDayBit) === 0) {
+        return false;
+      }
+      if (timeperiod.every === c.ZBX_MAINTENANCE_LAST_WEEK) {
+        const daysInMonth = new Date(day.getFullYear(), day.getMonth() + 1, 0).getDate();
+        return day.getDate() + 7 > daysInMonth;
+      }
+      return Math.ceil(day.getDate() / 7) === every;
+    }
+    default:
+      return false;
+  }
+}
+
+function startOfDay(timestamp) {
+  const date = new Date(timestamp);
+  return new Date(date.getFullYear(), date.getMonth(), date.getDate());
+}
+
 function splitKeyParams(paramStr) {
   let params = [];
   let quoted = false;
@@ -159,7 +291,31 @@ export function buildRegex(str) {
   var matches = str.match(regexPattern);
   var pattern = matches[1];
   var flags = matches[2] !== "" ? matches[2] : undefined;
-  return new RegExp(pattern, flags);
+  return compileRegex(pattern, flags);
+}
+
+const REGEX_CACHE_SIZE = 1000;
+const regexCache = new Map();
+
+/**
+ * Create RegExp or get it from cache, so the same filters aren't compiled on each refresh.
+ * Regex with global or sticky flag is stateful (lastIndex), so it's never cached.
+ */
+export function compileRegex(pattern, flags) {
+  if (flags && /[gy]/.test(flags)) {
+    return new RegExp(pattern, flags);
+  }
+
+  const key = `${flags || ''}/${pattern}`;
+  let regex = regexCache.get(key);
+  if (!regex) {
+    if (regexCache.size >= REGEX_CACHE_SIZE) {
+      regexCache.clear();
+    }
+    regex = new RegExp(pattern, flags);
+    regexCache.set(key, regex);
+  }
+  return regex;
 }
 
 // Need for template variables replace
@@ -168,6 +324,110 @@ export function escapeRegex(value) {
   return value.replace(/[\\^$*+?.()|[\]{}\/]/g, '\\$&');
 }
 
+// Pattern for host filters matched against host interfaces, ie ip:10.1.2.0/24 or dns:*.example.com
+const interfaceFilterPattern = /^(ip|dns):(.+)$/;
+
+/**
+ * Parse host filter which refers to host interface address.
+ * @return {object} {field, value} or null if filter is not an interface filter
+ */
+export function parseInterfaceFilter(filter) {
+  let matches = interfaceFilterPattern.exec(filter);
+  if (!matches) {
+    return null;
+  }
+  return { field: matches[1], value: matches[2].trim() };
+}
+
+/**
+ * Check if interface matches filter returned by parseInterfaceFilter().
+ * IP filter supports CIDR notation, dns filter supports * wildcard. Both accept regex.
+ */
+export function matchInterface(hostInterface, interfaceFilter) {
+  let { field, value } = interfaceFilter;
+  let address = hostInterface[field];
+  if (!address) {
+    return false;
+  }
+
+  if (isRegex(value)) {
+    return buildRegex(value).test(address);
+  } else if (field === 'ip' && value.indexOf('/') !== -1) {
+    return isIPInSubnet(address, value);
+  } else if (value.indexOf('*') !== -1) {
+    let wildcardPattern = escapeRegex(value).replace(/\\\*/g, '.*');
+    return new RegExp(`^${wildcardPattern}$`, 'i').test(address);
+  }
+  return address.toLowerCase() === value.toLowerCase();
+}
+
+/**
+ * Check if IPv4 address belongs to subnet in CIDR notation, ie 10.1.2.0/24.
+ */
+export function isIPInSubnet(ip, cidr) {
+  let [subnet, prefixLength] = cidr.split('/');
+  let prefix = Number(prefixLength);
+  let ipNum = ipv4ToNumber(ip);
+  let subnetNum = ipv4ToNumber(subnet);
+  if (ipNum === null || subnetNum === null || !(prefix >= 0 && prefix <= 32)) {
+    return false;
+  }
+
+  // Use division instead of bitwise shift, because JS bitwise operators work with signed 32-bit numbers
+  let blockSize = Math.pow(2, 32 - prefix);
+  return Math.floor(ipNum / blockSize) === Math.floor(subnetNum / blockSize);
+}
+
+function ipv4ToNumber(ip) {
+  let octets = ip.split('.');
+  if (octets.length !== 4) {
+    return null;
+  }
+
+  let result = 0;
+  for (let octet of octets) {
+    let octetNum = Number(octet);
+    if (octet === '' || !Number.isInteger(octetNum) || octetNum < 0 || octetNum > 255) {
+      return null;
+    }
+    result = result * 256 + octetNum;
+  }
+  return result;
+}
+
+const ITEM_TAG_CONDITION_PATTERN = /^(!)?([^=!~]+?)\s*(?:(=~|!~|!=|=)\s*(.*))?$/;
+const ITEM_TAG_OPERATORS = {
+  '=': c.ZBX_TAG_OPERATOR_EQUAL,
+  '!=': c.ZBX_TAG_OPERATOR_NOT_EQUAL,
+  '=~': c.ZBX_TAG_OPERATOR_LIKE,
+  '!~': c.ZBX_TAG_OPERATOR_NOT_LIKE,
+};
+
+/**
+ * Parse item tag filter into list of conditions for item.get "tags" param. Filter is a comma
+ * separated list of conditions:
+ * tag, !tag - tag exists / doesn't exist
+ * tag=value, tag!=value - tag value equals / not equals
+ * tag=~value, tag!~value - tag value contains / doesn't contain
+ * @return {Array} [{tag, value, operator}, ...]
+ */
+export function parseItemTagFilter(filter) {
+  let conditions = _.compact(_.map(_.split(filter, ','), _.trim));
+  return _.compact(_.map(conditions, condition => {
+    let match = ITEM_TAG_CONDITION_PATTERN.exec(condition);
+    // Negation is supported only for tag existence check
+    if (!match || (match[1] && match[3])) {
+      return null;
+    }
+    let [, negate, tag, operator, value] = match;
+    if (!operator) {
+      let tagOperator = negate ? c.ZBX_TAG_OPERATOR_NOT_EXISTS : c.ZBX_TAG_OPERATOR_EXISTS;
+      return { tag, value: '', operator: tagOperator };
+    }
+    return { tag, value, operator: ITEM_TAG_OPERATORS[operator] };
+  }));
+}
+
 export function parseInterval(interval) {
   var intervalPattern = /(^[\d]+)(y|M|w|d|h|m|s)/g;
   var momentInterval = intervalPattern.exec(interval);
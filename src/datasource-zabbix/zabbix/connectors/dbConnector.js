@@ -1,4 +1,5 @@
 import _ from 'lodash';
+import * as utils from '../../utils';
 
 export const DEFAULT_QUERY_LIMIT = 10000;
 export const HISTORY_TO_TABLE_MAP = {
@@ -124,25 +125,31 @@ export class ZabbixNotImplemented {
 function convertGrafanaTSResponse(time_series, items, addHostName) {
   //uniqBy is needed to deduplicate
   var hosts = _.uniqBy(_.flatten(_.map(items, 'hosts')), 'hostid');
-  let grafanaSeries = _.map(_.compact(time_series), series => {
+  // Skip series of unknown items instead of failing whole query
+  let knownSeries = _.filter(_.compact(time_series), series => _.some(items, {'itemid': series.name}));
+  if (knownSeries.length < _.compact(time_series).length) {
+    console.warn(`Zabbix: skipped ${_.compact(time_series).length - knownSeries.length} series of unknown items`);
+  }
+  let grafanaSeries = _.map(knownSeries, series => {
     let itemid = series.name;
     var item = _.find(items, {'itemid': itemid});
+    var host = _.find(hosts, {'hostid': item.hostid});
     var alias = item.name;
     //only when actual multi hosts selected
-    if (_.keys(hosts).length > 1 && addHostName) {
-      var host = _.find(hosts, {'hostid': item.hostid});
+    if (_.keys(hosts).length > 1 && addHostName && host) {
       alias = host.name + ": " + alias;
     }
     // CachingProxy deduplicates requests and returns one time series for equal queries.
     // Clone is needed to prevent changing of series object shared between all targets.
-    let datapoints = _.cloneDeep(series.points);
+    let datapoints = utils.normalizeDatapoints(_.cloneDeep(series.points));
     return {
       target: alias,
-      datapoints: datapoints
+      datapoints: datapoints,
+      tags: utils.getSeriesTags(item, host)
     };
   });
 
-  return _.sortBy(grafanaSeries, 'target');
+  return _.sortBy(grafanaSeries, [series => series.tags.host, series => series.tags.item]);
 }
 
 const defaults = {
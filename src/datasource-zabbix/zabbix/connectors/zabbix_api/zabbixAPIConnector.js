@@ -1,8 +1,14 @@
 import _ from 'lodash';
 import kbn from 'grafana/app/core/utils/kbn';
 import * as utils from '../../../utils';
-import { ZabbixAPICore } from './zabbixAPICore';
-import { ZBX_ACK_ACTION_NONE, ZBX_ACK_ACTION_ACK, ZBX_ACK_ACTION_ADD_MESSAGE, MIN_SLA_INTERVAL } from '../../../constants';
+import { ZabbixAPICore, ZabbixAPIError } from './zabbixAPICore';
+import {
+  ZBX_ACK_ACTION_NONE, ZBX_ACK_ACTION_ACK, ZBX_ACK_ACTION_ADD_MESSAGE, ZBX_ACK_ACTION_CHANGE_SEVERITY, MIN_SLA_INTERVAL,
+  ZBX_APPLICATION_TAG, ZBX_TAG_OPERATOR_EQUAL
+} from '../../../constants';
+
+// Max number of hosts in single item.get request
+const ITEMS_REQUEST_HOSTS_CHUNK = 500;
 
 /**
  * Zabbix API Wrapper.
@@ -10,18 +16,57 @@ import { ZBX_ACK_ACTION_NONE, ZBX_ACK_ACTION_ACK, ZBX_ACK_ACTION_ADD_MESSAGE, MI
  * Wraps API calls and provides high-level methods.
  */
 export class ZabbixAPIConnector {
-  constructor(api_url, username, password, version, basicAuth, withCredentials, backendSrv) {
-    this.url              = api_url;
+  constructor(options, backendSrv) {
+    const {
+      url,
+      access,
+      username,
+      password,
+      zabbixVersion,
+      basicAuth,
+      withCredentials,
+      jsonrpcVersion,
+      apiContentType,
+      sendNullAuth,
+      requestHeaders,
+      requestRetries,
+      slowRequestThreshold,
+      traceRequests,
+      useTechnicalHostName,
+      inventoryFields,
+    } = options;
+
+    this.url              = url;
     this.username         = username;
     this.password         = password;
     this.auth             = '';
-    this.version          = version;
+    this.version          = zabbixVersion;
+
+    // Grafana data proxy treats Authorization: Bearer header as Grafana API key (and replaces it if
+    // basic auth is configured), so header auth can be used only with direct access.
+    this.directAccess = access === 'direct';
+
+    // Host field used for filtering hosts by name
+    this.hostNameField = useTechnicalHostName ? 'host' : 'name';
+
+    // Host inventory fields added to series labels
+    this.inventoryFields = inventoryFields;
 
     this.requestOptions = {
       basicAuth: basicAuth,
-      withCredentials: withCredentials
+      withCredentials: withCredentials,
+      jsonrpcVersion: jsonrpcVersion,
+      contentType: apiContentType,
+      sendNullAuth: sendNullAuth,
+      headers: requestHeaders,
+      retries: requestRetries,
+      slowRequestThreshold: slowRequestThreshold,
+      trace: traceRequests
     };
 
+    this.apiVersion = null;
+    this.versionPromise = null;
+
     this.loginPromise = null;
     this.loginErrorCount = 0;
     this.maxLoginAttempts = 3;
@@ -37,9 +82,9 @@ export class ZabbixAPIConnector {
   //////////////////////////
 
   request(method, params) {
-    return this.zabbixAPICore.request(this.url, method, params, this.requestOptions, this.auth)
+    return this.zabbixAPICore.request(this.url, method, params, this.getRequestOptions(), this.auth)
     .catch(error => {
-      if (isNotAuthorized(error.data)) {
+      if (error instanceof ZabbixAPIError && error.isNotAuthorized()) {
         // Handle auth errors
         this.loginErrorCount++;
         if (this.loginErrorCount > this.maxLoginAttempts) {
@@ -64,7 +109,9 @@ export class ZabbixAPIConnector {
   loginOnce() {
     if (!this.loginPromise) {
       this.loginPromise = Promise.resolve(
-        this.login().then(auth => {
+        this.initVersion()
+        .then(() => this.login())
+        .then(auth => {
           this.auth = auth;
           this.loginPromise = null;
           return auth;
@@ -74,11 +121,44 @@ export class ZabbixAPIConnector {
     return this.loginPromise;
   }
 
+  /**
+   * Build request options adapted to detected Zabbix API version.
+   */
+  getRequestOptions() {
+    return Object.assign({}, this.requestOptions, {
+      bearerAuth: this.directAccess && !this.requestOptions.basicAuth && isBearerAuthSupported(this.apiVersion),
+      loginUsernameField: isVersionAtLeast(this.apiVersion, 5, 4) ? 'username' : 'user'
+    });
+  }
+
+  /**
+   * Detect Zabbix API version once. It's used for adapting requests to the server version,
+   * detected major version takes precedence over configured one.
+   */
+  initVersion() {
+    if (!this.versionPromise) {
+      this.versionPromise = this.getVersion()
+      .then(version => {
+        this.apiVersion = utils.parseVersion(version);
+        if (this.apiVersion) {
+          this.version = this.apiVersion.major;
+        }
+      })
+      .catch(() => {
+        // Try again next time, login should not fail because of version request
+        this.versionPromise = null;
+      });
+    }
+    return this.versionPromise;
+  }
+
   /**
    * Get authentication token.
    */
   login() {
-    return this.zabbixAPICore.login(this.url, this.username, this.password, this.requestOptions);
+    // Login params depend on API version, so detect it first (login may be called directly, e.g. on testing datasource)
+    return this.initVersion()
+    .then(() => this.zabbixAPICore.login(this.url, this.username, this.password, this.getRequestOptions()));
   }
 
   /**
@@ -92,14 +172,25 @@ export class ZabbixAPIConnector {
   // Zabbix API method wrappers //
   ////////////////////////////////
 
-  acknowledgeEvent(eventid, message) {
-    const action = this.version >= 4 ? ZBX_ACK_ACTION_ACK + ZBX_ACK_ACTION_ADD_MESSAGE : ZBX_ACK_ACTION_NONE;
+  /**
+   * Acknowledge event with message and optionally change its severity (Zabbix 4.0+).
+   */
+  acknowledgeEvent(eventid, message, severity) {
+    let action = this.version >= 4 ? ZBX_ACK_ACTION_ACK + ZBX_ACK_ACTION_ADD_MESSAGE : ZBX_ACK_ACTION_NONE;
     const params = {
       eventids: eventid,
       message: message,
-      action: action
     };
 
+    if (severity !== undefined && severity !== null) {
+      if (this.version < 4) {
+        return Promise.reject({ message: 'Changing problem severity requires Zabbix 4.0 or above.' });
+      }
+      action += ZBX_ACK_ACTION_CHANGE_SEVERITY;
+      params.severity = severity;
+    }
+
+    params.action = action;
     return this.request('event.acknowledge', params);
   }
 
@@ -113,25 +204,84 @@ export class ZabbixAPIConnector {
     return this.request('hostgroup.get', params);
   }
 
-  getHosts(groupids) {
+  getHosts(groupids, templateids, hostName) {
     var params = {
-      output: ['name', 'host'],
-      sortfield: 'name'
+      output: ['name', 'host', 'maintenance_status'],
+      sortfield: 'name',
+      selectInterfaces: ['ip', 'dns', 'main', 'type']
     };
     if (groupids) {
       params.groupids = groupids;
     }
+    if (templateids) {
+      params.templateids = templateids;
+    }
+    if (hostName) {
+      params.filter = { [this.hostNameField]: hostName };
+    }
+    if (this.inventoryFields && this.inventoryFields.length) {
+      params.selectInventory = this.inventoryFields;
+    }
 
     return this.request('host.get', params);
   }
 
-  getApps(hostids) {
+  getHostInterfaces(hostids) {
     var params = {
-      output: 'extend',
+      output: ['hostid', 'ip', 'dns'],
       hostids: hostids
     };
 
-    return this.request('application.get', params);
+    return this.request('hostinterface.get', params);
+  }
+
+  getTemplates() {
+    var params = {
+      output: ['name', 'host'],
+      sortfield: 'name'
+    };
+
+    return this.request('template.get', params);
+  }
+
+  getApps(hostids) {
+    return this.initVersion()
+    .then(() => {
+      if (this.isItemTagsSupported()) {
+        return this.getAppsFromItemTags(hostids);
+      }
+
+      var params = {
+        output: 'extend',
+        hostids: hostids
+      };
+
+      return this.request('application.get', params);
+    });
+  }
+
+  /**
+   * Applications were removed in Zabbix 5.4 and converted to "Application" item tags. Build list
+   * of applications from these tags, tag value is used as application id.
+   */
+  getAppsFromItemTags(hostids) {
+    var params = {
+      output: ['itemid'],
+      hostids: hostids,
+      webitems: true,
+      selectTags: 'extend'
+    };
+
+    return this.request('item.get', params)
+    .then(items => {
+      let appTags = _.filter(_.flatMap(items, 'tags'), { tag: ZBX_APPLICATION_TAG });
+      let appNames = _.uniq(_.map(appTags, 'value'));
+      return _.map(appNames, name => ({ applicationid: name, name: name }));
+    });
+  }
+
+  isItemTagsSupported() {
+    return isVersionAtLeast(this.apiVersion, 5, 4);
   }
 
   /**
@@ -139,28 +289,52 @@ export class ZabbixAPIConnector {
    * @param  {[type]} hostids  host ids
    * @param  {[type]} appids   application ids
    * @param  {String} itemtype 'num' or 'text'
+   * @param  {Array}  itemTags item tag conditions {tag, value, operator} (Zabbix 5.4+)
    * @return {[type]}          array of items
    */
-  getItems(hostids, appids, itemtype) {
+  getItems(hostids, appids, itemtype, itemTags) {
+    // Split request for large number of hosts, single item.get may time out on large installs
+    if (hostids && hostids.length > ITEMS_REQUEST_HOSTS_CHUNK) {
+      let chunks = _.chunk(hostids, ITEMS_REQUEST_HOSTS_CHUNK);
+      return chunks.reduce((promise, hostidsChunk) => {
+        return promise.then(items => {
+          return this.getItems(hostidsChunk, appids, itemtype, itemTags)
+          .then(chunkItems => items.concat(chunkItems));
+        });
+      }, Promise.resolve([]))
+      // Each chunk is sorted by name, keep the same order as single request (by name before macros expanding)
+      .then(items => _.sortBy(items, 'item'));
+    }
+
     var params = {
       output: [
         'name', 'key_',
         'value_type',
         'hostid',
         'status',
-        'state'
+        'state',
+        'error'
       ],
       sortfield: 'name',
       webitems: true,
       filter: {},
-      selectHosts: ['hostid', 'name']
+      selectHosts: ['hostid', 'name', 'host', 'maintenance_status']
     };
     if (hostids) {
       params.hostids = hostids;
     }
-    if (appids) {
+    let tags = itemTags ? itemTags.slice() : [];
+    if (appids && this.isItemTagsSupported()) {
+      // Applications are obtained from item tags on Zabbix 5.4+ (see getAppsFromItemTags()), conditions
+      // with the same tag name are joined with OR, so item matches any of given applications.
+      tags = tags.concat(_.map(appids, value => ({ tag: ZBX_APPLICATION_TAG, value, operator: ZBX_TAG_OPERATOR_EQUAL })));
+    } else if (appids) {
       params.applicationids = appids;
     }
+    if (tags.length) {
+      params.evaltype = 0;
+      params.tags = tags;
+    }
     if (itemtype === 'num') {
       // Return only numeric metrics
       params.filter.value_type = [0, 3];
@@ -174,6 +348,18 @@ export class ZabbixAPIConnector {
     .then(utils.expandItems);
   }
 
+  /**
+   * Get last values of items. Result isn't cached, so it's suitable for values used in calculations.
+   */
+  getItemsLastValues(itemids) {
+    var params = {
+      itemids: itemids,
+      output: ['itemid', 'hostid', 'lastvalue']
+    };
+
+    return this.request('item.get', params);
+  }
+
   getItemsByIDs(itemids) {
     var params = {
       itemids: itemids,
@@ -182,7 +368,8 @@ export class ZabbixAPIConnector {
         'value_type',
         'hostid',
         'status',
-        'state'
+        'state',
+        'error'
       ],
       webitems: true,
       selectHosts: ['hostid', 'name']
@@ -344,12 +531,17 @@ export class ZabbixAPIConnector {
         value: 1
       },
       selectGroups: ['name'],
-      selectHosts: ['name', 'host', 'maintenance_status', 'proxy_hostid'],
+      selectHosts: ['name', 'host', 'maintenance_status', 'maintenanceid', 'proxy_hostid'],
       selectItems: ['name', 'key_', 'lastvalue'],
       selectLastEvent: 'extend',
       selectTags: 'extend'
     };
 
+    // Application ids are built from item tags on Zabbix 5.4+, trigger.get can't filter by them
+    if (this.isItemTagsSupported()) {
+      delete params.applicationids;
+    }
+
     if (showTriggers) {
       params.filter.value = showTriggers;
     }
@@ -476,7 +668,7 @@ export class ZabbixAPIConnector {
       params.countOutput = true;
     }
 
-    if (applicationids && applicationids.length) {
+    if (applicationids && applicationids.length && !this.isItemTagsSupported()) {
       params.applicationids = applicationids;
     }
 
@@ -497,6 +689,39 @@ export class ZabbixAPIConnector {
     });
   }
 
+  /**
+   * Get host groups with status of their hosts.
+   */
+  getGroupsHostsStatus(groupids) {
+    var params = {
+      output: ['name'],
+      groupids: groupids,
+      real_hosts: true,
+      selectHosts: ['hostid', 'status'],
+      sortfield: 'name'
+    };
+
+    return this.request('hostgroup.get', params);
+  }
+
+  /**
+   * Get triggers in problem state with their groups. Only fields required for counting are returned,
+   * so it's cheap enough for large installations.
+   */
+  getGroupsProblems(groupids, minSeverity) {
+    var params = {
+      output: ['triggerid', 'priority'],
+      groupids: groupids,
+      min_severity: minSeverity,
+      filter: { value: 1 },
+      monitored: true,
+      skipDependent: true,
+      selectGroups: ['groupid']
+    };
+
+    return this.request('trigger.get', params);
+  }
+
   getProxies() {
     var params = {
       output: ['proxyid', 'host'],
@@ -504,6 +729,58 @@ export class ZabbixAPIConnector {
 
     return this.request('proxy.get', params);
   }
+
+  getProxiesStatus() {
+    var params = {
+      output: ['proxyid', 'host', 'status', 'lastaccess'],
+      selectHosts: ['hostid'],
+      sortfield: 'host'
+    };
+
+    return this.request('proxy.get', params);
+  }
+
+  getMaintenancesByIds(maintenanceids) {
+    var params = {
+      output: ['maintenanceid', 'name'],
+      maintenanceids: maintenanceids
+    };
+
+    return this.request('maintenance.get', params);
+  }
+
+  /**
+   * Get maintenances assigned to given hosts and active within given time range.
+   */
+  getMaintenances(hostids, timeFrom, timeTo) {
+    var params = {
+      output: 'extend',
+      hostids: hostids,
+      selectHosts: ['hostid', 'name'],
+      selectTimeperiods: 'extend'
+    };
+
+    return this.request('maintenance.get', params)
+    .then(maintenances => {
+      return _.filter(maintenances, m => {
+        return Number(m.active_since) <= timeTo && Number(m.active_till) >= timeFrom;
+      });
+    });
+  }
+}
+
+function isVersionAtLeast(version, major, minor) {
+  if (!version) {
+    return false;
+  }
+  return version.major > major || (version.major === major && version.minor >= minor);
+}
+
+/**
+ * Zabbix 6.4+ accepts auth token in Authorization header and deprecates auth field.
+ */
+function isBearerAuthSupported(version) {
+  return isVersionAtLeast(version, 6, 4);
 }
 
 function filterTriggersByAcknowledge(triggers, acknowledged) {
@@ -516,14 +793,6 @@ function filterTriggersByAcknowledge(triggers, acknowledged) {
   }
 }
 
-function isNotAuthorized(message) {
-  return (
-    message === "Session terminated, re-login, please." ||
-    message === "Not authorised." ||
-    message === "Not authorized."
-  );
-}
-
 function getSLAInterval(intervalMs) {
   // Too many intervals may cause significant load on the database, so decrease number of resulting points
   const resolutionRatio = 100;
@@ -0,0 +1,159 @@
+import _ from 'lodash';
+import { ZabbixAPIConnector } from './zabbixAPIConnector';
+
+describe('ZabbixAPIConnector', () => {
+  let zabbixAPI;
+
+  beforeEach(() => {
+    zabbixAPI = new ZabbixAPIConnector({ url: 'http://zabbix/api_jsonrpc.php' }, {});
+  });
+
+  describe('When getting items for large number of hosts', () => {
+    beforeEach(() => {
+      zabbixAPI.request = jest.fn((method, params) => {
+        return Promise.resolve(_.map(params.hostids, hostid => ({ hostid, name: 'CPU load', key_: 'system.cpu.load' })));
+      });
+    });
+
+    it('should split request into chunks', done => {
+      const hostids = _.map(_.range(1200), String);
+      zabbixAPI.getItems(hostids, undefined, 'num').then(items => {
+        expect(zabbixAPI.request).toHaveBeenCalledTimes(3);
+        expect(zabbixAPI.request.mock.calls[0][1].hostids.length).toBe(500);
+        expect(zabbixAPI.request.mock.calls[2][1].hostids.length).toBe(200);
+        expect(items.length).toBe(1200);
+        done();
+      });
+    });
+
+    it('should sort items from all chunks by name', done => {
+      zabbixAPI.request = jest.fn((method, params) => {
+        const names = params.hostids[0] === '0' ? ['CPU load', 'Memory'] : ['Disk', 'Network'];
+        return Promise.resolve(_.map(names, name => ({ hostid: params.hostids[0], name, key_: name })));
+      });
+      const hostids = _.map(_.range(600), String);
+      zabbixAPI.getItems(hostids, undefined, 'num').then(items => {
+        expect(_.map(items, 'name')).toEqual(['CPU load', 'Disk', 'Memory', 'Network']);
+        done();
+      });
+    });
+
+    it('should send single request for small number of hosts', done => {
+      zabbixAPI.getItems(['1', '2'], undefined, 'num').then(items => {
+        expect(zabbixAPI.request).toHaveBeenCalledTimes(1);
+        expect(items.length).toBe(2);
+        done();
+      });
+    });
+  });
+
+  describe('When using Zabbix 5.4+', () => {
+    beforeEach(() => {
+      zabbixAPI.apiVersion = { major: 5, minor: 4, patch: 0 };
+      zabbixAPI.initVersion = jest.fn().mockResolvedValue();
+      zabbixAPI.request = jest.fn().mockResolvedValue([
+        { itemid: '1', tags: [{ tag: 'Application', value: 'CPU' }, { tag: 'component', value: 'system' }] },
+        { itemid: '2', tags: [{ tag: 'Application', value: 'CPU' }] },
+        { itemid: '3', tags: [{ tag: 'Application', value: 'Memory' }] },
+      ]);
+    });
+
+    it('should build applications from item tags', done => {
+      zabbixAPI.getApps(['101']).then(apps => {
+        expect(zabbixAPI.request.mock.calls[0][0]).toBe('item.get');
+        expect(apps).toEqual([
+          { applicationid: 'CPU', name: 'CPU' },
+          { applicationid: 'Memory', name: 'Memory' },
+        ]);
+        done();
+      });
+    });
+
+    it('should map applications to item tags', done => {
+      const itemTags = [{ tag: 'component', value: 'system', operator: 1 }];
+      zabbixAPI.getItems(['101'], ['CPU', 'Memory'], 'num', itemTags).then(() => {
+        const params = zabbixAPI.request.mock.calls[0][1];
+        expect(params.applicationids).toBeUndefined();
+        expect(params.tags).toEqual([
+          { tag: 'component', value: 'system', operator: 1 },
+          { tag: 'Application', value: 'CPU', operator: 1 },
+          { tag: 'Application', value: 'Memory', operator: 1 },
+        ]);
+        done();
+      });
+    });
+  });
+
+  describe('When requesting host by name', () => {
+    it('should filter by visible name by default', () => {
+      zabbixAPI.request = jest.fn().mockResolvedValue([]);
+      zabbixAPI.getHosts(['1'], undefined, 'backend01');
+      expect(zabbixAPI.request.mock.calls[0][1].filter).toEqual({ name: 'backend01' });
+    });
+
+    it('should filter by technical name if option is enabled', () => {
+      zabbixAPI = new ZabbixAPIConnector({ url: 'http://zabbix/api_jsonrpc.php', useTechnicalHostName: true }, {});
+      zabbixAPI.request = jest.fn().mockResolvedValue([]);
+      zabbixAPI.getHosts(['1'], undefined, 'backend01');
+      expect(zabbixAPI.request.mock.calls[0][1].filter).toEqual({ host: 'backend01' });
+    });
+
+    it('should select configured inventory fields', () => {
+      zabbixAPI = new ZabbixAPIConnector({ url: 'http://zabbix/api_jsonrpc.php', inventoryFields: ['location'] }, {});
+      zabbixAPI.request = jest.fn().mockResolvedValue([]);
+      zabbixAPI.getHosts(['1']);
+      expect(zabbixAPI.request.mock.calls[0][1].selectInventory).toEqual(['location']);
+    });
+  });
+
+  describe('When choosing auth method', () => {
+    const version = { major: 6, minor: 4, patch: 0 };
+
+    it('should use bearer auth for direct access to Zabbix 6.4+', () => {
+      zabbixAPI = new ZabbixAPIConnector({ url: 'http://zabbix/api_jsonrpc.php', access: 'direct' }, {});
+      zabbixAPI.apiVersion = version;
+      expect(zabbixAPI.getRequestOptions().bearerAuth).toBe(true);
+    });
+
+    it('should keep auth in request body for proxy access', () => {
+      zabbixAPI = new ZabbixAPIConnector({ url: 'http://zabbix/api_jsonrpc.php', access: 'proxy' }, {});
+      zabbixAPI.apiVersion = version;
+      expect(zabbixAPI.getRequestOptions().bearerAuth).toBe(false);
+    });
+  });
+
+  describe('When logging in', () => {
+    let backendSrv;
+
+    beforeEach(() => {
+      backendSrv = {
+        datasourceRequest: jest.fn(request => {
+          const result = request.data.method === 'apiinfo.version' ? '6.4.0' : 'token';
+          return Promise.resolve({ status: 200, data: { result } });
+        })
+      };
+      zabbixAPI = new ZabbixAPIConnector({ url: 'http://zabbix/api_jsonrpc.php', username: 'Admin', password: 'zabbix' }, backendSrv);
+    });
+
+    it('should detect version and use username field for Zabbix 5.4+', done => {
+      zabbixAPI.login().then(auth => {
+        const methods = backendSrv.datasourceRequest.mock.calls.map(call => call[0].data.method);
+        expect(methods).toEqual(['apiinfo.version', 'user.login']);
+        expect(backendSrv.datasourceRequest.mock.calls[1][0].data.params).toEqual({ username: 'Admin', password: 'zabbix' });
+        expect(auth).toBe('token');
+        done();
+      });
+    });
+
+    it('should use user field for older versions', done => {
+      backendSrv.datasourceRequest.mockImplementation(request => {
+        const result = request.data.method === 'apiinfo.version' ? '5.0.10' : 'token';
+        return Promise.resolve({ status: 200, data: { result } });
+      });
+      zabbixAPI.login().then(() => {
+        expect(backendSrv.datasourceRequest.mock.calls[1][0].data.params).toEqual({ user: 'Admin', password: 'zabbix' });
+        done();
+      });
+    });
+  });
+});
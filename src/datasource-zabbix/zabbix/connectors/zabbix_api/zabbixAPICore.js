@@ -2,11 +2,31 @@
  * General Zabbix API methods
  */
 
+import { ZBX_API_ERROR_INVALID_PARAMS } from '../../../constants';
+
+const DEFAULT_JSONRPC_VERSION = '2.0';
+const DEFAULT_CONTENT_TYPE = 'application/json';
+const NON_JSON_SNIPPET_LENGTH = 200;
+const RETRY_BASE_DELAY = 500;
+const RETRY_MAX_DELAY = 10000;
+const CIRCUIT_BREAKER_THRESHOLD = 5;
+const CIRCUIT_BREAKER_COOLDOWN = 30000;
+const SLOW_REQUEST_MAX_PARAM_ITEMS = 5;
+const TRACE_RESPONSE_LENGTH = 1000;
+const REDACTED = '******';
+
+// Zabbix returns "Session terminated, re-login, please." or "Not authorised." with invalid params code
+const NOT_AUTHORIZED_PATTERN = /re-login|not authori[sz]ed/i;
+
 export class ZabbixAPICore {
 
   /** @ngInject */
   constructor(backendSrv) {
     this.backendSrv = backendSrv;
+
+    // Circuit breaker state: fail fast if Zabbix API is unreachable
+    this.failureCount = 0;
+    this.circuitOpenUntil = 0;
   }
 
   /**
@@ -15,7 +35,7 @@ export class ZabbixAPICore {
    */
   request(api_url, method, params, options, auth) {
     let requestData = {
-      jsonrpc: '2.0',
+      jsonrpc: options.jsonrpcVersion || DEFAULT_JSONRPC_VERSION,
       method: method,
       params: params,
       id: 1
@@ -23,19 +43,22 @@ export class ZabbixAPICore {
 
     if (auth === "") {
       // Reject immediately if not authenticated
-      return Promise.reject(new ZabbixAPIError({data: "Not authorised."}));
-    } else if (auth) {
+      return Promise.reject(new ZabbixAPIError({code: ZBX_API_ERROR_INVALID_PARAMS, data: "Not authorised."}));
+    } else if (auth && !options.bearerAuth) {
       // Set auth parameter only if it needed
       requestData.auth = auth;
+    } else if (auth === null && options.sendNullAuth) {
+      // Some old frontends require auth field in login request
+      requestData.auth = null;
     }
 
     let requestOptions = {
       method: 'POST',
       url: api_url,
       data: requestData,
-      headers: {
-        'Content-Type': 'application/json'
-      }
+      headers: Object.assign({
+        'Content-Type': options.contentType || DEFAULT_CONTENT_TYPE
+      }, options.headers)
     };
 
     // Set request options for basic auth
@@ -44,16 +67,47 @@ export class ZabbixAPICore {
     }
     if (options.basicAuth) {
       requestOptions.headers.Authorization = options.basicAuth;
+    } else if (auth && options.bearerAuth) {
+      requestOptions.headers.Authorization = 'Bearer ' + auth;
     }
 
-    return this.datasourceRequest(requestOptions);
+    // Only read requests are safe to retry
+    const retries = isReadMethod(method) ? options.retries : 0;
+    const startTime = Date.now();
+    if (options.trace) {
+      console.log(`Zabbix API: trace request ${method}`, redactRequest(requestOptions));
+    }
+
+    return this.datasourceRequest(requestOptions, retries)
+    .then(result => {
+      logSlowRequest(method, params, result, startTime, options.slowRequestThreshold);
+      if (options.trace) {
+        console.log(`Zabbix API: trace response ${method}`, redactResponse(method, result));
+      }
+      return result;
+    }, error => {
+      logSlowRequest(method, params, null, startTime, options.slowRequestThreshold);
+      if (options.trace) {
+        console.log(`Zabbix API: trace error ${method}`, error && error.message ? error.message : error);
+      }
+      return Promise.reject(error);
+    });
   }
 
-  datasourceRequest(requestOptions) {
-    return this.backendSrv.datasourceRequest(requestOptions)
+  datasourceRequest(requestOptions, retries = 0) {
+    const circuitError = this.checkCircuit();
+    if (circuitError) {
+      return Promise.reject(circuitError);
+    }
+
+    return this.sendRequest(requestOptions, retries)
     .then((response) => {
+      this.failureCount = 0;
       if (!response.data) {
         return Promise.reject(new ZabbixAPIError({data: "General Error, no data"}));
+      } else if (typeof response.data === 'string') {
+        // Frontend returned HTML page or other non-JSON content (PHP error, proxy error page, etc)
+        return Promise.reject(getNonJSONResponseError(response));
       } else if (response.data.error) {
 
         // Handle Zabbix API errors
@@ -62,6 +116,50 @@ export class ZabbixAPICore {
 
       // Success
       return response.data.result;
+    }, (error) => {
+      if (isTransientError(error)) {
+        this.registerFailure();
+      }
+      if (error && typeof error.data === 'string' && error.data) {
+        return Promise.reject(getNonJSONResponseError(error));
+      }
+      return Promise.reject(error);
+    });
+  }
+
+  /**
+   * Return error if circuit is open, so request shouldn't be sent. After cool-down period
+   * requests are allowed again and next failure opens circuit immediately.
+   */
+  checkCircuit() {
+    const remaining = this.circuitOpenUntil - Date.now();
+    if (remaining > 0) {
+      return new ZabbixAPIError({
+        message: 'Zabbix unreachable,',
+        data: `retrying in ${Math.ceil(remaining / 1000)}s`
+      });
+    }
+    return null;
+  }
+
+  registerFailure() {
+    this.failureCount++;
+    if (this.failureCount >= CIRCUIT_BREAKER_THRESHOLD) {
+      this.circuitOpenUntil = Date.now() + CIRCUIT_BREAKER_COOLDOWN;
+    }
+  }
+
+  /**
+   * Send request and retry it with exponential backoff if network error or 5xx response occurred.
+   */
+  sendRequest(requestOptions, retries, attempt = 0) {
+    return this.backendSrv.datasourceRequest(requestOptions)
+    .catch(error => {
+      if (attempt >= retries || !isTransientError(error)) {
+        return Promise.reject(error);
+      }
+      return delay(getRetryDelay(attempt))
+      .then(() => this.sendRequest(requestOptions, retries, attempt + 1));
     });
   }
 
@@ -70,8 +168,9 @@ export class ZabbixAPICore {
    * @return {string}  auth token
    */
   login(api_url, username, password, options) {
+    // Zabbix 5.4+ uses 'username' field instead of deprecated 'user'
     let params = {
-      user: username,
+      [options.loginUsernameField || 'user']: username,
       password: password
     };
     return this.request(api_url, 'user.login', params, options, null);
@@ -86,6 +185,118 @@ export class ZabbixAPICore {
   }
 }
 
+function isReadMethod(method) {
+  return method === 'apiinfo.version' || /\.get$/.test(method);
+}
+
+/**
+ * Warn about requests which take longer than threshold (ms) to find queries hurting Zabbix server.
+ */
+function logSlowRequest(method, params, result, startTime, threshold) {
+  const elapsed = Date.now() - startTime;
+  if (!threshold || elapsed < threshold) {
+    return;
+  }
+  console.warn(`Zabbix API: slow request ${method} took ${elapsed}ms`, {
+    method: method,
+    params: summarizeParams(params),
+    resultCount: Array.isArray(result) ? result.length : null,
+    elapsed: elapsed
+  });
+}
+
+/**
+ * Shorten long id lists and hide password for logging.
+ */
+function summarizeParams(params) {
+  if (!params || typeof params !== 'object' || Array.isArray(params)) {
+    return params;
+  }
+  let summary = {};
+  for (let key of Object.keys(params)) {
+    const value = params[key];
+    if (key === 'password') {
+      summary[key] = '******';
+    } else if (Array.isArray(value) && value.length > SLOW_REQUEST_MAX_PARAM_ITEMS) {
+      summary[key] = `[${value.length} items]`;
+    } else {
+      summary[key] = value;
+    }
+  }
+  return summary;
+}
+
+/**
+ * Copy request options with auth token, password and Authorization header hidden.
+ */
+function redactRequest(requestOptions) {
+  let data = Object.assign({}, requestOptions.data);
+  if (data.auth) {
+    data.auth = REDACTED;
+  }
+  if (data.params && data.params.password !== undefined) {
+    data.params = Object.assign({}, data.params, { password: REDACTED });
+  }
+  let headers = Object.assign({}, requestOptions.headers);
+  if (headers.Authorization) {
+    headers.Authorization = REDACTED;
+  }
+  return Object.assign({}, requestOptions, { data, headers });
+}
+
+/**
+ * Serialize and truncate response. Login response is an auth token, so it's hidden.
+ */
+function redactResponse(method, result) {
+  if (method === 'user.login') {
+    return REDACTED;
+  }
+  let text = JSON.stringify(result) || '';
+  if (text.length > TRACE_RESPONSE_LENGTH) {
+    text = text.slice(0, TRACE_RESPONSE_LENGTH) + `... (${text.length} chars)`;
+  }
+  return text;
+}
+
+function isTransientError(error) {
+  if (!error || error.status === undefined) {
+    return false;
+  }
+  // Network error or timeout (status -1 or 0), or server side error
+  return error.status <= 0 || error.status === 408 || error.status >= 500;
+}
+
+/**
+ * Exponential backoff with jitter: random delay between half and full backoff interval.
+ */
+function getRetryDelay(attempt) {
+  const backoff = Math.min(RETRY_BASE_DELAY * Math.pow(2, attempt), RETRY_MAX_DELAY);
+  return backoff / 2 + Math.random() * backoff / 2;
+}
+
+function delay(ms) {
+  return new Promise(resolve => setTimeout(resolve, ms));
+}
+
+/**
+ * Build concise error from non-JSON response instead of dumping whole HTML page.
+ */
+function getNonJSONResponseError(response) {
+  let text = response.data
+    .replace(/<(script|style)[^>]*>[\s\S]*?<\/\1>/gi, ' ')
+    .replace(/<[^>]*>/g, ' ')
+    .replace(/\s+/g, ' ')
+    .trim();
+  if (text.length > NON_JSON_SNIPPET_LENGTH) {
+    text = text.slice(0, NON_JSON_SNIPPET_LENGTH) + '...';
+  }
+  const status = response.status ? ` (status ${response.status})` : '';
+  return new ZabbixAPIError({
+    message: `Zabbix frontend returned non-JSON response${status}:`,
+    data: text
+  });
+}
+
 // Define zabbix API exception type
 export class ZabbixAPIError {
   constructor(error) {
@@ -95,6 +306,13 @@ export class ZabbixAPIError {
     this.message = "Zabbix API Error: " + this.name + " " + this.data;
   }
 
+  /**
+   * Auth token is missing, expired or terminated, so re-login is required.
+   */
+  isNotAuthorized() {
+    return this.code === ZBX_API_ERROR_INVALID_PARAMS && NOT_AUTHORIZED_PATTERN.test(this.data);
+  }
+
   toString() {
     return this.name + " " + this.data;
   }
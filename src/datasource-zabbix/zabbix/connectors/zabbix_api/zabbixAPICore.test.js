@@ -0,0 +1,235 @@
+import { ZabbixAPICore, ZabbixAPIError } from './zabbixAPICore';
+
+describe('ZabbixAPICore', () => {
+  let backendSrv;
+  let zabbixAPICore;
+
+  beforeEach(() => {
+    backendSrv = { datasourceRequest: jest.fn() };
+    zabbixAPICore = new ZabbixAPICore(backendSrv);
+  });
+
+  it('should return result of successful request', done => {
+    backendSrv.datasourceRequest.mockResolvedValue({ status: 200, data: { result: '4.0.0' } });
+    zabbixAPICore.getVersion('http://zabbix/api_jsonrpc.php', {}).then(result => {
+      expect(result).toBe('4.0.0');
+      done();
+    });
+  });
+
+  it('should return concise error for HTML page', done => {
+    const page = '<html><head><style>body {}</style></head><body><h1>PHP Fatal error</h1> Allowed memory size exhausted</body></html>';
+    backendSrv.datasourceRequest.mockResolvedValue({ status: 200, data: page });
+    zabbixAPICore.getVersion('http://zabbix/api_jsonrpc.php', {}).catch(error => {
+      expect(error).toBeInstanceOf(ZabbixAPIError);
+      expect(error.message).toBe(
+        'Zabbix API Error: Zabbix frontend returned non-JSON response (status 200): PHP Fatal error Allowed memory size exhausted'
+      );
+      done();
+    });
+  });
+
+  it('should truncate non-JSON error response', done => {
+    const page = '<html><body>' + 'Bad Gateway '.repeat(50) + '</body></html>';
+    backendSrv.datasourceRequest.mockRejectedValue({ status: 502, data: page });
+    zabbixAPICore.getVersion('http://zabbix/api_jsonrpc.php', {}).catch(error => {
+      expect(error.name).toBe('Zabbix frontend returned non-JSON response (status 502):');
+      expect(error.data.length).toBe(203);
+      done();
+    });
+  });
+
+  it('should retry read request after 5xx response', done => {
+    backendSrv.datasourceRequest
+      .mockRejectedValueOnce({ status: 503, data: {} })
+      .mockResolvedValueOnce({ status: 200, data: { result: [] } });
+    zabbixAPICore.request('http://zabbix/api_jsonrpc.php', 'host.get', {}, { retries: 2 }, 'token').then(result => {
+      expect(backendSrv.datasourceRequest).toHaveBeenCalledTimes(2);
+      expect(result).toEqual([]);
+      done();
+    });
+  });
+
+  it('should not retry write requests', done => {
+    backendSrv.datasourceRequest.mockRejectedValue({ status: 503, data: {} });
+    zabbixAPICore.request('http://zabbix/api_jsonrpc.php', 'event.acknowledge', {}, { retries: 2 }, 'token').catch(() => {
+      expect(backendSrv.datasourceRequest).toHaveBeenCalledTimes(1);
+      done();
+    });
+  });
+
+  describe('When request is slow', () => {
+    let dateNow;
+    let consoleWarn;
+
+    beforeEach(() => {
+      dateNow = jest.spyOn(Date, 'now').mockReturnValueOnce(1000).mockReturnValue(7000);
+      consoleWarn = jest.spyOn(console, 'warn').mockImplementation(() => {});
+      backendSrv.datasourceRequest.mockResolvedValue({ status: 200, data: { result: [{}, {}] } });
+    });
+
+    afterEach(() => {
+      dateNow.mockRestore();
+      consoleWarn.mockRestore();
+    });
+
+    it('should log warning with request summary', done => {
+      const params = { output: ['name'], hostids: ['1', '2', '3', '4', '5', '6'] };
+      zabbixAPICore.request('http://zabbix/api_jsonrpc.php', 'item.get', params, { slowRequestThreshold: 5000 }, 'token')
+      .then(() => {
+        expect(console.warn).toHaveBeenCalledWith('Zabbix API: slow request item.get took 6000ms', {
+          method: 'item.get',
+          params: { output: ['name'], hostids: '[6 items]' },
+          resultCount: 2,
+          elapsed: 6000
+        });
+        done();
+      });
+    });
+
+    it('should not log if threshold is not set', done => {
+      zabbixAPICore.request('http://zabbix/api_jsonrpc.php', 'item.get', {}, {}, 'token').then(() => {
+        expect(console.warn).not.toHaveBeenCalled();
+        done();
+      });
+    });
+  });
+
+  describe('When tracing is enabled', () => {
+    let consoleLog;
+
+    beforeEach(() => {
+      consoleLog = jest.spyOn(console, 'log').mockImplementation(() => {});
+    });
+
+    afterEach(() => {
+      consoleLog.mockRestore();
+    });
+
+    it('should log request without credentials', done => {
+      backendSrv.datasourceRequest.mockResolvedValue({ status: 200, data: { result: 'secret-token' } });
+      const options = { trace: true, basicAuth: 'Basic dXNlcjpwYXNz' };
+      zabbixAPICore.login('http://zabbix/api_jsonrpc.php', 'admin', 'zabbix', options).then(() => {
+        const loggedRequest = console.log.mock.calls[0][1];
+        expect(loggedRequest.data.params).toEqual({ user: 'admin', password: '******' });
+        expect(loggedRequest.headers.Authorization).toBe('******');
+        expect(console.log.mock.calls[1][1]).toBe('******');
+        expect(JSON.stringify(console.log.mock.calls)).not.toMatch(/zabbix"|secret-token|dXNlcjpwYXNz/);
+        done();
+      });
+    });
+
+    it('should truncate long responses', done => {
+      backendSrv.datasourceRequest.mockResolvedValue({ status: 200, data: { result: 'x'.repeat(2000) } });
+      zabbixAPICore.request('http://zabbix/api_jsonrpc.php', 'item.get', {}, { trace: true }, 'token').then(() => {
+        expect(console.log.mock.calls[0][1].data.auth).toBe('******');
+        expect(console.log.mock.calls[1][1]).toBe('"' + 'x'.repeat(999) + '... (2002 chars)');
+        done();
+      });
+    });
+  });
+
+  describe('When Zabbix API is unreachable', () => {
+    const request = () => zabbixAPICore.request('http://zabbix/api_jsonrpc.php', 'host.get', {}, {}, 'token');
+    const failRequests = n => {
+      let promise = Promise.resolve();
+      for (let i = 0; i < n; i++) {
+        promise = promise.then(() => request().catch(() => {}));
+      }
+      return promise;
+    };
+
+    beforeEach(() => {
+      backendSrv.datasourceRequest.mockRejectedValue({ status: -1, data: null });
+    });
+
+    it('should fail fast after repeated failures', done => {
+      failRequests(5)
+      .then(() => request())
+      .catch(error => {
+        expect(backendSrv.datasourceRequest).toHaveBeenCalledTimes(5);
+        expect(error.message).toBe('Zabbix API Error: Zabbix unreachable, retrying in 30s');
+        done();
+      });
+    });
+
+    it('should not open circuit on API errors', done => {
+      backendSrv.datasourceRequest.mockResolvedValue({ status: 200, data: { error: { code: -32602, data: 'Invalid params.' } } });
+      failRequests(5)
+      .then(() => request())
+      .catch(() => {
+        expect(backendSrv.datasourceRequest).toHaveBeenCalledTimes(6);
+        done();
+      });
+    });
+
+    it('should send requests again after cool-down', done => {
+      failRequests(5)
+      .then(() => {
+        zabbixAPICore.circuitOpenUntil = Date.now() - 1;
+        backendSrv.datasourceRequest.mockResolvedValue({ status: 200, data: { result: [] } });
+        return request();
+      })
+      .then(result => {
+        expect(result).toEqual([]);
+        expect(zabbixAPICore.failureCount).toBe(0);
+        done();
+      });
+    });
+  });
+
+  describe('When sending auth token', () => {
+    beforeEach(() => {
+      backendSrv.datasourceRequest.mockResolvedValue({ status: 200, data: { result: [] } });
+    });
+
+    it('should send token in request body by default', done => {
+      zabbixAPICore.request('http://zabbix/api_jsonrpc.php', 'host.get', {}, {}, 'token').then(() => {
+        const requestOptions = backendSrv.datasourceRequest.mock.calls[0][0];
+        expect(requestOptions.data.auth).toBe('token');
+        expect(requestOptions.headers.Authorization).toBeUndefined();
+        done();
+      });
+    });
+
+    it('should send token in Authorization header if bearer auth enabled', done => {
+      zabbixAPICore.request('http://zabbix/api_jsonrpc.php', 'host.get', {}, { bearerAuth: true }, 'token').then(() => {
+        const requestOptions = backendSrv.datasourceRequest.mock.calls[0][0];
+        expect(requestOptions.data.auth).toBeUndefined();
+        expect(requestOptions.headers.Authorization).toBe('Bearer token');
+        done();
+      });
+    });
+  });
+
+  describe('ZabbixAPIError', () => {
+    it('should detect expired session', () => {
+      const error = new ZabbixAPIError({ code: -32602, message: 'Invalid params.', data: 'Session terminated, re-login, please.' });
+      expect(error.isNotAuthorized()).toBe(true);
+    });
+
+    it('should detect missing auth', () => {
+      const error = new ZabbixAPIError({ code: -32602, message: 'Invalid params.', data: 'Not authorized.' });
+      expect(error.isNotAuthorized()).toBe(true);
+    });
+
+    it('should not treat permission errors as not authorized', () => {
+      const error = new ZabbixAPIError({
+        code: -32500, message: 'Application error.', data: 'No permissions to referred object or it does not exist!'
+      });
+      expect(error.isNotAuthorized()).toBe(false);
+    });
+
+    it('should not treat other invalid params errors as not authorized', () => {
+      const error = new ZabbixAPIError({ code: -32602, message: 'Invalid params.', data: 'Incorrect method "host.foo".' });
+      expect(error.isNotAuthorized()).toBe(false);
+    });
+
+    it('should reject request without auth token as not authorized', done => {
+      zabbixAPICore.request('http://zabbix/api_jsonrpc.php', 'host.get', {}, {}, '').catch(error => {
+        expect(error.isNotAuthorized()).toBe(true);
+        done();
+      });
+    });
+  });
+});
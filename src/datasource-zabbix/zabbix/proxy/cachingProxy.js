@@ -50,6 +50,8 @@ export class CachingProxy {
 /**
  * Wrap request to prevent multiple calls
  * with same params when waiting for result.
+ * Combined with cacheRequest() it makes only one request to hit the API
+ * when cached value expires, concurrent callers wait for the same promise.
  */
 function callOnce(func, promiseKeeper, funcScope) {
   return function() {
@@ -60,6 +62,10 @@ function callOnce(func, promiseKeeper, funcScope) {
         .then(result => {
           promiseKeeper[hash] = null;
           return result;
+        }, error => {
+          // Don't keep failed request, otherwise all subsequent calls will fail too
+          promiseKeeper[hash] = null;
+          return Promise.reject(error);
         })
       );
     }
@@ -0,0 +1,51 @@
+import { CachingProxy } from './cachingProxy';
+
+describe('CachingProxy', () => {
+  let cachingProxy;
+
+  beforeEach(() => {
+    cachingProxy = new CachingProxy({ enabled: true, ttl: 60000 });
+  });
+
+  it('should make only one request for concurrent calls with expired cache', done => {
+    const request = jest.fn().mockResolvedValue(['foo']);
+    const proxyfied = cachingProxy.proxyfyWithCache(request, 'getFoo');
+
+    Promise.all([proxyfied('a'), proxyfied('a'), proxyfied('a')]).then(results => {
+      expect(request).toHaveBeenCalledTimes(1);
+      expect(results).toEqual([['foo'], ['foo'], ['foo']]);
+      done();
+    });
+  });
+
+  it('should return cached value while it is up to date', done => {
+    const request = jest.fn().mockResolvedValue(['foo']);
+    const proxyfied = cachingProxy.proxyfyWithCache(request, 'getFoo');
+
+    proxyfied('a')
+    .then(() => proxyfied('a'))
+    .then(result => {
+      expect(request).toHaveBeenCalledTimes(1);
+      expect(result).toEqual(['foo']);
+      done();
+    });
+  });
+
+  it('should retry request after failure', done => {
+    const request = jest.fn()
+      .mockRejectedValueOnce(new Error('timeout'))
+      .mockResolvedValueOnce(['foo']);
+    const proxyfied = cachingProxy.proxyfy(request, 'getFoo');
+
+    proxyfied('a')
+    .catch(error => {
+      expect(error.message).toBe('timeout');
+      return proxyfied('a');
+    })
+    .then(result => {
+      expect(request).toHaveBeenCalledTimes(2);
+      expect(result).toEqual(['foo']);
+      done();
+    });
+  });
+});
@@ -11,37 +11,42 @@ import { InfluxDBConnector } from './connectors/influxdb/influxdbConnector';
 const REQUESTS_TO_PROXYFY = [
   'getHistory', 'getTrend', 'getGroups', 'getHosts', 'getApps', 'getItems', 'getMacros', 'getItemsByIDs',
   'getEvents', 'getAlerts', 'getHostAlerts', 'getAcknowledges', 'getITService', 'getSLA', 'getVersion', 'getProxies',
-  'getEventAlerts', 'getExtendedEventData'
+  'getEventAlerts', 'getExtendedEventData', 'getTemplates', 'getProxiesStatus', 'getMaintenances',
+  'getMaintenancesByIds', 'getHostInterfaces', 'getItemsLastValues', 'getGroupsHostsStatus', 'getGroupsProblems'
 ];
 
 const REQUESTS_TO_CACHE = [
-  'getGroups', 'getHosts', 'getApps', 'getItems', 'getMacros', 'getItemsByIDs', 'getITService', 'getProxies'
+  'getGroups', 'getHosts', 'getApps', 'getItems', 'getMacros', 'getItemsByIDs', 'getITService', 'getProxies',
+  'getTemplates', 'getHostInterfaces'
 ];
 
 const REQUESTS_TO_BIND = [
   'getHistory', 'getTrend', 'getMacros', 'getItemsByIDs', 'getEvents', 'getAlerts', 'getHostAlerts',
   'getAcknowledges', 'getITService', 'getVersion', 'login', 'acknowledgeEvent', 'getProxies', 'getEventAlerts',
-  'getExtendedEventData'
+  'getExtendedEventData', 'getMaintenances', 'getMaintenancesByIds', 'getItemsLastValues', 'getGroupsHostsStatus',
+  'getGroupsProblems'
 ];
 
 export class Zabbix {
   constructor(options, datasourceSrv, backendSrv) {
     let {
-      url,
-      username,
-      password,
-      basicAuth,
-      withCredentials,
-      zabbixVersion,
       cacheTTL,
       enableDirectDBConnection,
       dbConnectionDatasourceId,
       dbConnectionDatasourceName,
       dbConnectionRetentionPolicy,
+      caseInsensitiveFilters,
+      useTechnicalHostName,
     } = options;
 
     this.enableDirectDBConnection = enableDirectDBConnection;
 
+    // Match plain (non-regex) filters ignoring case
+    this.caseInsensitiveFilters = caseInsensitiveFilters;
+
+    // Use technical host name instead of visible one for filtering and series names
+    this.useTechnicalHostName = useTechnicalHostName;
+
     // Initialize caching proxy for requests
     let cacheOptions = {
       enabled: true,
@@ -49,7 +54,7 @@ export class Zabbix {
     };
     this.cachingProxy = new CachingProxy(cacheOptions);
 
-    this.zabbixAPI = new ZabbixAPIConnector(url, username, password, zabbixVersion, basicAuth, withCredentials, backendSrv);
+    this.zabbixAPI = new ZabbixAPIConnector(options, backendSrv);
 
     this.proxyfyRequests();
     this.cacheRequests();
@@ -145,15 +150,22 @@ export class Zabbix {
   getItemsFromTarget(target, options) {
     let parts = ['group', 'host', 'application', 'item'];
     let filters = _.map(parts, p => target[p].filter);
+    options = Object.assign({}, options, {
+      templateFilter: getTemplateFilter(target),
+      itemTagFilter: target.itemTag ? target.itemTag.filter : '',
+      dedupItems: target.options && target.options.dedupItems,
+      skipMaintenanceHosts: target.options && target.options.skipMaintenanceHosts
+    });
     return this.getItems(...filters, options);
   }
 
   getHostsFromTarget(target) {
     let parts = ['group', 'host', 'application'];
-    let filters = _.map(parts, p => target[p].filter);
+    let [groupFilter, hostFilter, appFilter] = _.map(parts, p => target[p].filter);
+    let templateFilter = getTemplateFilter(target);
     return Promise.all([
-      this.getHosts(...filters),
-      this.getApps(...filters),
+      this.getHosts(groupFilter, hostFilter, templateFilter),
+      this.getApps(groupFilter, hostFilter, appFilter, templateFilter),
     ]).then((results) => {
       let [hosts, apps] = results;
       if (apps.appFilterEmpty) {
@@ -169,23 +181,111 @@ export class Zabbix {
 
   getGroups(groupFilter) {
     return this.getAllGroups()
-    .then(groups => findByFilter(groups, groupFilter));
+    .then(groups => findByFilter(groups, groupFilter, this.caseInsensitiveFilters));
   }
 
   /**
    * Get list of host belonging to given groups.
+   * @param hostName exact host name, if set only this host is requested from Zabbix
    */
-  getAllHosts(groupFilter) {
+  getAllHosts(groupFilter, hostName) {
     return this.getGroups(groupFilter)
     .then(groups => {
       let groupids = _.map(groups, 'groupid');
-      return this.zabbixAPI.getHosts(groupids);
+      return this.zabbixAPI.getHosts(groupids, undefined, hostName);
+    })
+    .then(hosts => this.useTechnicalHostName ? setTechnicalHostNames(hosts) : hosts);
+  }
+
+  /**
+   * Get matched host groups with ids of their hosts.
+   */
+  getGroupsWithHosts(groupFilter) {
+    return this.getGroups(groupFilter)
+    .then(groups => {
+      if (!groups.length) {
+        return [];
+      }
+      // Get hosts of all groups with single request
+      return this.zabbixAPI.getGroupsHostsStatus(_.map(groups, 'groupid'))
+      .then(groupsHosts => {
+        let groupsIndex = _.keyBy(groupsHosts, 'groupid');
+        return _.map(groups, group => {
+          let hosts = groupsIndex[group.groupid] ? groupsIndex[group.groupid].hosts : [];
+          return { name: group.name, groupid: group.groupid, hostids: _.map(hosts, 'hostid') };
+        });
+      });
     });
   }
 
-  getHosts(groupFilter, hostFilter) {
-    return this.getAllHosts(groupFilter)
-    .then(hosts => findByFilter(hosts, hostFilter));
+  getHosts(groupFilter, hostFilter, templateFilter) {
+    // Let Zabbix filter hosts by exact name instead of fetching all hosts of the groups
+    let hostName = this.isPlainFilter(hostFilter) ? hostFilter : undefined;
+    return this.getAllHosts(groupFilter, hostName)
+    .then(hosts => this.filterHosts(hosts, hostFilter))
+    .then(hosts => this.filterHostsByTemplate(hosts, templateFilter));
+  }
+
+  /**
+   * Filter hosts by name or by interface address if filter looks like
+   * ip:10.1.2.0/24 or dns:*.prod.example.com
+   */
+  filterHosts(hosts, hostFilter) {
+    let interfaceFilter = utils.parseInterfaceFilter(hostFilter);
+    if (!interfaceFilter) {
+      return Promise.resolve(findByFilter(hosts, hostFilter, this.caseInsensitiveFilters));
+    }
+
+    let hostids = _.map(hosts, 'hostid');
+    if (!hostids.length) {
+      return Promise.resolve([]);
+    }
+
+    return this.zabbixAPI.getHostInterfaces(hostids)
+    .then(interfaces => {
+      let matchedInterfaces = _.filter(interfaces, i => utils.matchInterface(i, interfaceFilter));
+      let matchedHostIds = _.uniq(_.map(matchedInterfaces, 'hostid'));
+      return _.filter(hosts, host => _.includes(matchedHostIds, host.hostid));
+    });
+  }
+
+  /**
+   * Plain filter is matched by exact name, so it can be passed to Zabbix API.
+   */
+  isPlainFilter(filter) {
+    return !!filter && !this.caseInsensitiveFilters && !utils.isRegex(filter) && !utils.parseInterfaceFilter(filter);
+  }
+
+  getAllTemplates() {
+    return this.zabbixAPI.getTemplates();
+  }
+
+  getTemplates(templateFilter) {
+    return this.getAllTemplates()
+    .then(templates => findByFilter(templates, templateFilter, this.caseInsensitiveFilters));
+  }
+
+  /**
+   * Keep only hosts linked to templates matched by given filter.
+   * Empty filter means no filtering.
+   */
+  filterHostsByTemplate(hosts, templateFilter) {
+    if (!templateFilter) {
+      return Promise.resolve(hosts);
+    }
+
+    return this.getTemplates(templateFilter)
+    .then(templates => {
+      let templateids = _.map(templates, 'templateid');
+      if (!templateids.length) {
+        return [];
+      }
+      return this.zabbixAPI.getHosts(undefined, templateids);
+    })
+    .then(linkedHosts => {
+      let linkedHostIds = _.map(linkedHosts, 'hostid');
+      return _.filter(hosts, host => _.includes(linkedHostIds, host.hostid));
+    });
   }
 
   /**
@@ -199,13 +299,13 @@ export class Zabbix {
     });
   }
 
-  getApps(groupFilter, hostFilter, appFilter) {
-    return this.getHosts(groupFilter, hostFilter)
+  getApps(groupFilter, hostFilter, appFilter, templateFilter) {
+    return this.getHosts(groupFilter, hostFilter, templateFilter)
     .then(hosts => {
       let hostids = _.map(hosts, 'hostid');
       if (appFilter) {
         return this.zabbixAPI.getApps(hostids)
-        .then(apps => filterByQuery(apps, appFilter));
+        .then(apps => filterByQuery(apps, appFilter, this.caseInsensitiveFilters));
       } else {
         return {
           appFilterEmpty: true,
@@ -215,20 +315,49 @@ export class Zabbix {
     });
   }
 
+  /**
+   * Get items of matched hosts. If application filter is set, only items from matched
+   * applications of these hosts are returned.
+   */
   getAllItems(groupFilter, hostFilter, appFilter, options = {}) {
-    return this.getApps(groupFilter, hostFilter, appFilter)
-    .then(apps => {
-      if (apps.appFilterEmpty) {
-        return this.zabbixAPI.getItems(apps.hostids, undefined, options.itemtype);
-      } else {
-        let appids = _.map(apps, 'applicationid');
-        return this.zabbixAPI.getItems(undefined, appids, options.itemtype);
+    let hostsIndex = {};
+    return this.getHosts(groupFilter, hostFilter, options.templateFilter)
+    .then(hosts => {
+      if (options.skipMaintenanceHosts) {
+        hosts = _.reject(hosts, utils.isHostInMaintenance);
+      }
+      hostsIndex = _.keyBy(hosts, 'hostid');
+      let hostids = _.uniq(_.map(hosts, 'hostid'));
+      let itemTags = options.itemTagFilter ? utils.parseItemTagFilter(options.itemTagFilter) : undefined;
+      if (!hostids.length) {
+        return [];
+      } else if (!appFilter) {
+        return this.zabbixAPI.getItems(hostids, undefined, options.itemtype, itemTags);
       }
+
+      return this.zabbixAPI.getApps(hostids)
+      .then(apps => filterByQuery(apps, appFilter, this.caseInsensitiveFilters))
+      .then(apps => {
+        let appids = _.map(apps, 'applicationid');
+        // Empty list of ids means no filtering for Zabbix API, so don't request all items
+        if (!appids.length) {
+          return [];
+        }
+        return this.zabbixAPI.getItems(hostids, appids, options.itemtype, itemTags);
+      });
     })
     .then(items => {
+      // Host belonging to several matched groups shouldn't produce the same item twice
+      items = _.uniqBy(items, 'itemid');
       if (!options.showDisabledItems) {
         items = _.filter(items, {'status': '0'});
       }
+      // Hosts returned by host.get contain interfaces and name chosen by datasource options,
+      // use them instead of hosts selected with items. Items are cloned, because they are cached.
+      items = _.map(items, item => {
+        let hosts = _.map(item.hosts, host => hostsIndex[host.hostid] || host);
+        return Object.assign({}, item, { hosts });
+      });
 
       return items;
     })
@@ -250,12 +379,13 @@ export class Zabbix {
 
   getItems(groupFilter, hostFilter, appFilter, itemFilter, options = {}) {
     return this.getAllItems(groupFilter, hostFilter, appFilter, options)
-    .then(items => filterByQuery(items, itemFilter));
+    .then(items => filterByQuery(items, itemFilter, this.caseInsensitiveFilters))
+    .then(items => options.dedupItems ? dedupItems(items) : items);
   }
 
   getITServices(itServiceFilter) {
     return this.zabbixAPI.getITService()
-    .then(itServices => findByFilter(itServices, itServiceFilter));
+    .then(itServices => findByFilter(itServices, itServiceFilter, this.caseInsensitiveFilters));
   }
 
   /**
@@ -313,10 +443,38 @@ export class Zabbix {
     return this.zabbixAPI.getProxies()
     .then(proxies => {
       proxies.forEach(proxy => proxy.name = proxy.host);
-      return findByFilter(proxies, proxyFilter);
+      return findByFilter(proxies, proxyFilter, this.caseInsensitiveFilters);
+    });
+  }
+
+  getProxiesStatus(proxyFilter) {
+    return this.zabbixAPI.getProxiesStatus()
+    .then(proxies => {
+      proxies.forEach(proxy => proxy.name = proxy.host);
+      return proxyFilter ? findByFilter(proxies, proxyFilter, this.caseInsensitiveFilters) : proxies;
     });
   }
 
+  /**
+   * Get matched host groups with their hosts status and problems for overview tables.
+   */
+  getHostGroupsOverview(groupFilter, minSeverity) {
+    // Empty filter means all groups
+    let getGroups = groupFilter ? this.getGroups(groupFilter) : this.getAllGroups();
+    return getGroups
+    .then(groups => {
+      let groupids = _.map(groups, 'groupid');
+      if (!groupids.length) {
+        return [[], []];
+      }
+      return Promise.all([
+        this.zabbixAPI.getGroupsHostsStatus(groupids),
+        this.zabbixAPI.getGroupsProblems(groupids, minSeverity)
+      ]);
+    })
+    .then(([groups, problems]) => ({ groups, problems }));
+  }
+
   getHistoryTS(items, timeRange, options) {
     let [timeFrom, timeTo] = timeRange;
     if (this.enableDirectDBConnection) {
@@ -334,6 +492,7 @@ export class Zabbix {
       return this.getTrendsDB(items, timeFrom, timeTo, options)
       .then(history => this.dbConnector.handleGrafanaTSResponse(history, items));
     } else {
+      // consolidateBy is resolved by datasource: consolidateBy() -> trendValue() -> datasource default
       let valueType = options.consolidateBy || options.valueType;
       return this.zabbixAPI.getTrend(items, timeFrom, timeTo)
       .then(history => responseHandler.handleTrends(history, items, valueType))
@@ -381,8 +540,8 @@ export class Zabbix {
  * @param  name visible name
  * @return      array with finded element or empty array
  */
-function findByName(list, name) {
-  var finded = _.find(list, {'name': name});
+function findByName(list, name, ignoreCase) {
+  var finded = _.find(list, getNameMatcher(name, ignoreCase));
   if (finded) {
     return [finded];
   } else {
@@ -398,8 +557,8 @@ function findByName(list, name) {
  * @param  {[type]} name app name
  * @return {[type]}      array with finded element or empty array
  */
-function filterByName(list, name) {
-  var finded = _.filter(list, {'name': name});
+function filterByName(list, name, ignoreCase) {
+  var finded = _.filter(list, getNameMatcher(name, ignoreCase));
   if (finded) {
     return finded;
   } else {
@@ -414,20 +573,57 @@ function filterByRegex(list, regex) {
   });
 }
 
-function findByFilter(list, filter) {
+function getNameMatcher(name, ignoreCase) {
+  if (!ignoreCase || !_.isString(name)) {
+    return {'name': name};
+  }
+  let lowerName = name.toLowerCase();
+  return zbx_obj => _.isString(zbx_obj.name) && zbx_obj.name.toLowerCase() === lowerName;
+}
+
+function findByFilter(list, filter, ignoreCase) {
   if (utils.isRegex(filter)) {
     return filterByRegex(list, filter);
   } else {
-    return findByName(list, filter);
+    return findByName(list, filter, ignoreCase);
   }
 }
 
-function filterByQuery(list, filter) {
+function filterByQuery(list, filter, ignoreCase) {
   if (utils.isRegex(filter)) {
     return filterByRegex(list, filter);
   } else {
-    return filterByName(list, filter);
+    return filterByName(list, filter, ignoreCase);
+  }
+}
+
+/**
+ * Remove items with the same name on the same host. Host linked to overlapping templates
+ * gets items with the same name but different keys (item key is unique per host), which
+ * produce indistinguishable series. Item with the lowest id is kept.
+ */
+function dedupItems(items) {
+  let keptItemIds = _.mapValues(
+    _.groupBy(items, item => `${item.hostid}:${item.name}`),
+    group => _.minBy(group, item => Number(item.itemid)).itemid
+  );
+  let uniqItems = _.filter(items, item => keptItemIds[`${item.hostid}:${item.name}`] === item.itemid);
+  if (uniqItems.length < items.length) {
+    console.debug(`Zabbix: ${items.length - uniqItems.length} items with duplicate names removed`);
   }
+  return uniqItems;
+}
+
+/**
+ * Replace visible host names with technical ones. Hosts are cloned, because source objects are shared
+ * through the request cache.
+ */
+function setTechnicalHostNames(hosts) {
+  return _.map(hosts, host => Object.assign({}, host, { name: host.host || host.name }));
+}
+
+function getTemplateFilter(target) {
+  return target.template ? target.template.filter : '';
 }
 
 function getHostIds(items) {
@@ -1,3 +1,4 @@
+import _ from 'lodash';
 import mocks from '../../test-setup/mocks';
 import { Zabbix } from './zabbix';
 
@@ -95,4 +96,342 @@ describe('Zabbix', () => {
       });
     });
   });
+
+  describe('When filtering hosts by template', () => {
+    beforeEach(() => {
+      zabbix.zabbixAPI.getTemplates = jest.fn().mockResolvedValue([
+        { templateid: '10001', name: 'Template OS Linux' },
+        { templateid: '10002', name: 'Template App Nginx' },
+      ]);
+      zabbix.zabbixAPI.getHosts = jest.fn().mockResolvedValue([
+        { hostid: '20002', name: 'frontend01' },
+      ]);
+      ctx.hosts = [
+        { hostid: '20001', name: 'backend01' },
+        { hostid: '20002', name: 'frontend01' },
+      ];
+    });
+
+    it("should return all hosts for empty filter", done => {
+      zabbix.filterHostsByTemplate(ctx.hosts, '').then(hosts => {
+        expect(hosts).toEqual(ctx.hosts);
+        expect(zabbix.zabbixAPI.getTemplates).not.toHaveBeenCalled();
+        done();
+      });
+    });
+
+    it("should return hosts linked to matched templates", done => {
+      zabbix.filterHostsByTemplate(ctx.hosts, '/Nginx/').then(hosts => {
+        expect(zabbix.zabbixAPI.getHosts).toHaveBeenCalledWith(undefined, ['10002']);
+        expect(hosts).toEqual([{ hostid: '20002', name: 'frontend01' }]);
+        done();
+      });
+    });
+
+    it("should return empty list if no templates matched", done => {
+      zabbix.filterHostsByTemplate(ctx.hosts, 'Template DB').then(hosts => {
+        expect(zabbix.zabbixAPI.getHosts).not.toHaveBeenCalled();
+        expect(hosts).toEqual([]);
+        done();
+      });
+    });
+  });
+
+  describe('When querying proxies status', () => {
+    beforeEach(() => {
+      zabbix.zabbixAPI.getProxiesStatus = jest.fn().mockResolvedValue([
+        { host: 'proxy-foo', proxyid: '10101', status: '5', lastaccess: '1500000000', hosts: [] },
+        { host: 'proxy-bar', proxyid: '10102', status: '6', lastaccess: '0', hosts: [{ hostid: '1' }] },
+      ]);
+    });
+
+    it("should return all proxies for empty filter", done => {
+      zabbix.getProxiesStatus('').then(proxies => {
+        expect(proxies).toMatchObject([{ name: 'proxy-foo' }, { name: 'proxy-bar' }]);
+        done();
+      });
+    });
+
+    it("should return matched proxies if regex filter used", done => {
+      zabbix.getProxiesStatus('/.*-bar/').then(proxies => {
+        expect(proxies).toMatchObject([{ name: 'proxy-bar' }]);
+        done();
+      });
+    });
+  });
+
+  describe('When querying host groups with hosts', () => {
+    beforeEach(() => {
+      zabbix.zabbixAPI.getGroups = jest.fn().mockResolvedValue([
+        { groupid: '1', name: 'Backend' },
+        { groupid: '2', name: 'Frontend' },
+      ]);
+      zabbix.zabbixAPI.getGroupsHostsStatus = jest.fn().mockResolvedValue([
+        { groupid: '1', name: 'Backend', hosts: [{ hostid: '101', status: '0' }, { hostid: '102', status: '0' }] },
+        { groupid: '2', name: 'Frontend', hosts: [{ hostid: '201', status: '0' }] },
+      ]);
+    });
+
+    it("should get hosts of all groups with single request", done => {
+      zabbix.getGroupsWithHosts('/.*/').then(() => {
+        expect(zabbix.zabbixAPI.getGroupsHostsStatus).toHaveBeenCalledTimes(1);
+        expect(zabbix.zabbixAPI.getGroupsHostsStatus).toHaveBeenCalledWith(['1', '2']);
+        done();
+      });
+    });
+
+    it("should return host ids for each matched group", done => {
+      zabbix.getGroupsWithHosts('/.*/').then(groups => {
+        expect(groups).toEqual([
+          { name: 'Backend', groupid: '1', hostids: ['101', '102'] },
+          { name: 'Frontend', groupid: '2', hostids: ['201'] },
+        ]);
+        done();
+      });
+    });
+  });
+
+  describe('When deduplicating items', () => {
+    beforeEach(() => {
+      // backend01 is linked to two templates with "CPU load" item of different keys
+      zabbix.getHosts = jest.fn().mockResolvedValue([{ hostid: '101', name: 'backend01' }, { hostid: '102', name: 'backend02' }]);
+      zabbix.zabbixAPI.getItems = jest.fn().mockResolvedValue([
+        { itemid: '23', hostid: '101', key_: 'system.cpu.load[all,avg1]', name: 'CPU load', status: '0', hosts: [{ hostid: '101' }] },
+        { itemid: '21', hostid: '101', key_: 'system.cpu.load', name: 'CPU load', status: '0', hosts: [{ hostid: '101' }] },
+        { itemid: '31', hostid: '102', key_: 'system.cpu.load', name: 'CPU load', status: '0', hosts: [{ hostid: '102' }] },
+      ]);
+      zabbix.getMacros = jest.fn().mockResolvedValue([]);
+    });
+
+    it("should keep items with the same name by default", done => {
+      zabbix.getItems('/.*/', '/.*/', '', '/.*/').then(items => {
+        expect(_.map(items, 'itemid')).toEqual(['23', '21', '31']);
+        done();
+      });
+    });
+
+    it("should keep single item with the same name on the same host", done => {
+      zabbix.getItems('/.*/', '/.*/', '', '/.*/', { dedupItems: true }).then(items => {
+        expect(_.map(items, 'itemid')).toEqual(['21', '31']);
+        done();
+      });
+    });
+  });
+
+  describe('When filtering hosts by interface', () => {
+    beforeEach(() => {
+      zabbix.getAllHosts = jest.fn().mockResolvedValue([
+        { hostid: '101', name: 'backend01' },
+        { hostid: '102', name: 'backend02' },
+        { hostid: '103', name: 'frontend01' },
+      ]);
+      zabbix.zabbixAPI.getHostInterfaces = jest.fn().mockResolvedValue([
+        { hostid: '101', ip: '10.1.2.11', dns: 'backend01.prod.example.com' },
+        { hostid: '101', ip: '192.168.0.11', dns: '' },
+        { hostid: '102', ip: '10.1.3.12', dns: 'backend02.dev.example.com' },
+        { hostid: '103', ip: '10.1.2.13', dns: 'frontend01.prod.example.com' },
+      ]);
+    });
+
+    it("should return hosts with interfaces in subnet", done => {
+      zabbix.getHosts('/.*/', 'ip:10.1.2.0/24').then(hosts => {
+        expect(hosts).toMatchObject([{ hostid: '101' }, { hostid: '103' }]);
+        done();
+      });
+    });
+
+    it("should return hosts with matched dns name", done => {
+      zabbix.getHosts('/.*/', 'dns:*.dev.example.com').then(hosts => {
+        expect(hosts).toMatchObject([{ hostid: '102' }]);
+        done();
+      });
+    });
+
+    it("should not query interfaces for name filter", done => {
+      zabbix.getHosts('/.*/', 'backend02').then(hosts => {
+        expect(hosts).toMatchObject([{ hostid: '102' }]);
+        expect(zabbix.zabbixAPI.getHostInterfaces).not.toHaveBeenCalled();
+        done();
+      });
+    });
+  });
+
+  describe('When getting items with application filter', () => {
+    beforeEach(() => {
+      zabbix.getHosts = jest.fn().mockResolvedValue([{ hostid: '101' }, { hostid: '102' }]);
+      zabbix.zabbixAPI.getApps = jest.fn().mockResolvedValue([
+        { applicationid: '1', hostid: '101', name: 'CPU' },
+        { applicationid: '2', hostid: '102', name: 'CPU' },
+        { applicationid: '3', hostid: '102', name: 'Memory' },
+      ]);
+      zabbix.zabbixAPI.getItems = jest.fn().mockResolvedValue([]);
+      zabbix.getMacros = jest.fn().mockResolvedValue([]);
+    });
+
+    it("should pass both host and application ids", done => {
+      zabbix.getAllItems('/.*/', '/.*/', 'CPU').then(() => {
+        expect(zabbix.zabbixAPI.getApps).toHaveBeenCalledWith(['101', '102']);
+        expect(zabbix.zabbixAPI.getItems).toHaveBeenCalledWith(['101', '102'], ['1', '2'], undefined, undefined);
+        done();
+      });
+    });
+
+    it("should not request items if no applications matched", done => {
+      zabbix.getAllItems('/.*/', '/.*/', 'Disk').then(items => {
+        expect(items).toEqual([]);
+        expect(zabbix.zabbixAPI.getItems).not.toHaveBeenCalled();
+        done();
+      });
+    });
+  });
+
+  describe('When matching plain filters', () => {
+    beforeEach(() => {
+      zabbix.zabbixAPI.getGroups = jest.fn().mockResolvedValue([
+        { groupid: '1', name: 'Backend' },
+        { groupid: '2', name: 'Frontend' },
+      ]);
+    });
+
+    it("should match exact name by default", done => {
+      zabbix.getGroups('backend').then(groups => {
+        expect(groups).toEqual([]);
+        done();
+      });
+    });
+
+    it("should ignore case if option is enabled", done => {
+      zabbix.caseInsensitiveFilters = true;
+      zabbix.getGroups('backend').then(groups => {
+        expect(groups).toMatchObject([{ groupid: '1' }]);
+        done();
+      });
+    });
+  });
+
+  describe('When getting hosts by plain filter', () => {
+    beforeEach(() => {
+      zabbix.getGroups = jest.fn().mockResolvedValue([{ groupid: '1', name: 'Backend' }]);
+      zabbix.zabbixAPI.getHosts = jest.fn().mockResolvedValue([{ hostid: '101', name: 'backend01' }]);
+    });
+
+    it("should pass host name to Zabbix API", done => {
+      zabbix.getHosts('Backend', 'backend01').then(hosts => {
+        expect(zabbix.zabbixAPI.getHosts).toHaveBeenCalledWith(['1'], undefined, 'backend01');
+        expect(hosts).toMatchObject([{ hostid: '101' }]);
+        done();
+      });
+    });
+
+    it("should filter regex on the client side", done => {
+      zabbix.getHosts('Backend', '/backend/').then(() => {
+        expect(zabbix.zabbixAPI.getHosts).toHaveBeenCalledWith(['1'], undefined, undefined);
+        done();
+      });
+    });
+  });
+
+  describe('When skipping hosts in maintenance', () => {
+    beforeEach(() => {
+      zabbix.getHosts = jest.fn().mockResolvedValue([
+        { hostid: '101', name: 'backend01', maintenance_status: '0' },
+        { hostid: '102', name: 'backend02', maintenance_status: '1' },
+      ]);
+      zabbix.zabbixAPI.getItems = jest.fn().mockResolvedValue([]);
+      zabbix.getMacros = jest.fn().mockResolvedValue([]);
+    });
+
+    it("should request items of all hosts by default", done => {
+      zabbix.getAllItems('/.*/', '/.*/', '').then(() => {
+        expect(zabbix.zabbixAPI.getItems).toHaveBeenCalledWith(['101', '102'], undefined, undefined, undefined);
+        done();
+      });
+    });
+
+    it("should not request items of hosts in maintenance", done => {
+      zabbix.getAllItems('/.*/', '/.*/', '', { skipMaintenanceHosts: true }).then(() => {
+        expect(zabbix.zabbixAPI.getItems).toHaveBeenCalledWith(['101'], undefined, undefined, undefined);
+        done();
+      });
+    });
+  });
+
+  describe('When using technical host names', () => {
+    beforeEach(() => {
+      zabbix.useTechnicalHostName = true;
+      zabbix.getGroups = jest.fn().mockResolvedValue([{ groupid: '1', name: 'Backend' }]);
+      zabbix.zabbixAPI.getHosts = jest.fn().mockResolvedValue([
+        { hostid: '101', name: 'Backend server 01', host: 'backend01' },
+        { hostid: '102', name: 'Backend server 02', host: 'backend02' },
+      ]);
+    });
+
+    it("should filter hosts by technical name", done => {
+      zabbix.getHosts('Backend', '/backend01/').then(hosts => {
+        expect(hosts).toEqual([{ hostid: '101', name: 'backend01', host: 'backend01' }]);
+        done();
+      });
+    });
+  });
+
+  describe('When getting items', () => {
+    beforeEach(() => {
+      zabbix.getHosts = jest.fn().mockResolvedValue([
+        { hostid: '101', name: 'backend01', interfaces: [{ ip: '10.1.2.11', dns: '', main: '1', type: '1' }] },
+      ]);
+      zabbix.zabbixAPI.getItems = jest.fn().mockResolvedValue([
+        { itemid: '1', hostid: '101', name: 'CPU load', status: '0', hosts: [{ hostid: '101', name: 'backend01' }] },
+      ]);
+      zabbix.getMacros = jest.fn().mockResolvedValue([]);
+    });
+
+    it("should return each item once", done => {
+      zabbix.getHosts = jest.fn().mockResolvedValue([{ hostid: '101', name: 'backend01' }, { hostid: '101', name: 'backend01' }]);
+      zabbix.zabbixAPI.getItems = jest.fn().mockResolvedValue([
+        { itemid: '1', hostid: '101', name: 'CPU load', status: '0', hosts: [{ hostid: '101' }] },
+        { itemid: '1', hostid: '101', name: 'CPU load', status: '0', hosts: [{ hostid: '101' }] },
+      ]);
+      zabbix.getAllItems('/.*/', '/.*/', '').then(items => {
+        expect(zabbix.zabbixAPI.getItems).toHaveBeenCalledWith(['101'], undefined, undefined, undefined);
+        expect(items.length).toBe(1);
+        done();
+      });
+    });
+
+    it("should attach host details to items", done => {
+      zabbix.getAllItems('/.*/', '/.*/', '').then(items => {
+        expect(items[0].hosts).toEqual([
+          { hostid: '101', name: 'backend01', interfaces: [{ ip: '10.1.2.11', dns: '', main: '1', type: '1' }] },
+        ]);
+        done();
+      });
+    });
+  });
+
+  describe('When getting host groups overview', () => {
+    beforeEach(() => {
+      zabbix.getAllGroups = jest.fn().mockResolvedValue([
+        { groupid: '1', name: 'Backend' },
+        { groupid: '2', name: 'Frontend' },
+      ]);
+      zabbix.zabbixAPI.getGroupsHostsStatus = jest.fn().mockResolvedValue([]);
+      zabbix.zabbixAPI.getGroupsProblems = jest.fn().mockResolvedValue([]);
+    });
+
+    it("should request all groups for empty filter", done => {
+      zabbix.getHostGroupsOverview('', 3).then(() => {
+        expect(zabbix.zabbixAPI.getGroupsHostsStatus).toHaveBeenCalledWith(['1', '2']);
+        expect(zabbix.zabbixAPI.getGroupsProblems).toHaveBeenCalledWith(['1', '2'], 3);
+        done();
+      });
+    });
+
+    it("should not request hosts if no groups matched", done => {
+      zabbix.getHostGroupsOverview('Database', 3).then(result => {
+        expect(result).toEqual({ groups: [], problems: [] });
+        expect(zabbix.zabbixAPI.getGroupsHostsStatus).not.toHaveBeenCalled();
+        done();
+      });
+    });
+  });
 });
@@ -186,7 +186,7 @@ function AlertHost(props: AlertHostProps) {
 
   return (
     <span className="zabbix-hostname">
-      {problem.maintenance && <i className="fa fa-wrench zbx-maintenance-icon"></i>}
+      {problem.maintenance && <i className="fa fa-wrench zbx-maintenance-icon" title={problem.maintenanceName}></i>}
       {host}
     </span>
   );
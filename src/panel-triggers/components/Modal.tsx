@@ -1,9 +1,16 @@
 import React, { PureComponent } from 'react';
 import ReactDOM from 'react-dom';
 import classNames from 'classnames';
+import { TRIGGER_SEVERITY } from '../../datasource-zabbix/constants';
 
 const KEYBOARD_ENTER_KEY = 13;
 const KEYBOARD_ESCAPE_KEY = 27;
+const KEEP_SEVERITY = -1;
+
+const SEVERITY_OPTIONS = [
+  { value: KEEP_SEVERITY, label: 'Keep severity' },
+  ...TRIGGER_SEVERITY.map(severity => ({ value: severity.val, label: severity.text })),
+];
 
 interface ModalProps {
   isOpen?: boolean;
@@ -14,6 +21,7 @@ interface ModalProps {
 
 interface ModalState {
   value: string;
+  severity: number;
   error: boolean;
   message: string;
 }
@@ -22,6 +30,7 @@ export interface AckProblemData {
   message: string;
   closeProblem?: boolean;
   action?: number;
+  severity?: number;
 }
 
 export class Modal extends PureComponent<ModalProps, ModalState> {
@@ -31,6 +40,7 @@ export class Modal extends PureComponent<ModalProps, ModalState> {
     super(props);
     this.state = {
       value: '',
+      severity: KEEP_SEVERITY,
       error: false,
       message: '',
     };
@@ -42,6 +52,10 @@ export class Modal extends PureComponent<ModalProps, ModalState> {
     this.setState({ value: event.target.value, error: false });
   }
 
+  handleSeverityChange = (event: React.ChangeEvent<HTMLSelectElement>) => {
+    this.setState({ severity: Number(event.target.value) });
+  }
+
   handleKeyUp = (event: React.KeyboardEvent<HTMLInputElement>) => {
     if (event.which === KEYBOARD_ENTER_KEY || event.key === 'Enter') {
       this.submit();
@@ -55,7 +69,7 @@ export class Modal extends PureComponent<ModalProps, ModalState> {
   }
 
   dismiss = () => {
-    this.setState({ value: '', error: false, message: '' });
+    this.setState({ value: '', severity: KEEP_SEVERITY, error: false, message: '' });
     this.props.onClose();
   }
 
@@ -66,9 +80,11 @@ export class Modal extends PureComponent<ModalProps, ModalState> {
         message: 'Enter message text'
       });
     }
-    this.props.onSubmit({
-      message: this.state.value
-    }).then(() => {
+    const data: AckProblemData = { message: this.state.value };
+    if (this.state.severity !== KEEP_SEVERITY) {
+      data.severity = this.state.severity;
+    }
+    this.props.onSubmit(data).then(() => {
       this.dismiss();
     });
   }
@@ -114,6 +130,16 @@ export class Modal extends PureComponent<ModalProps, ModalState> {
               </label>
             </div>
 
+            <div className="gf-form">
+              <div className="gf-form-select-wrapper">
+                <select className="gf-form-input" value={this.state.severity} onChange={this.handleSeverityChange}>
+                  {SEVERITY_OPTIONS.map(option =>
+                    <option key={option.value} value={option.value}>{option.label}</option>
+                  )}
+                </select>
+              </div>
+            </div>
+
             <div className="gf-form-button-row text-center">
               <button className="btn btn-success" onClick={this.submit}>Acknowledge</button>
               <button className="btn btn-inverse" onClick={this.dismiss}>Cancel</button>
@@ -14,6 +14,7 @@ export default function ProblemStatusBar(props: ProblemStatusBarProps) {
   const multiEvent = problem.type === '1';
   const link = problem.url && problem.url !== '';
   const maintenance = problem.maintenance;
+  const maintenanceTooltip = problem.maintenanceName ? `Host maintenance: ${problem.maintenanceName}` : 'Host maintenance';
   const manualClose = problem.manual_close === '1';
   const error = problem.error && problem.error !== '';
   const stateUnknown = problem.state === '1';
@@ -23,7 +24,7 @@ export default function ProblemStatusBar(props: ProblemStatusBarProps) {
 
   return (
     <div className={`problem-statusbar ${className || ''}`}>
-      <ProblemStatusBarItem icon="wrench" fired={maintenance} tooltip="Host maintenance" />
+      <ProblemStatusBarItem icon="wrench" fired={maintenance} tooltip={maintenanceTooltip} />
       <ProblemStatusBarItem icon="globe" fired={link} link={link && problem.url} tooltip="External link" />
       <ProblemStatusBarItem icon="bullhorn" fired={multiEvent} tooltip="Trigger generates multiple problem events" />
       <ProblemStatusBarItem icon="tag" fired={closeByTag} tooltip={`OK event closes problems matched to tag: ${problem.correlation_tag}`} />
@@ -18,6 +18,7 @@ describe('TriggerPanelCtrl', () => {
         getTriggers: jest.fn().mockReturnValue([generateTrigger("1"), generateTrigger("1")]),
         getExtendedEventData: jest.fn().mockResolvedValue([]),
         getEventAlerts: jest.fn().mockResolvedValue([]),
+        getMaintenancesByIds: jest.fn().mockResolvedValue([]),
       }
     };
 
@@ -144,6 +145,34 @@ describe('TriggerPanelCtrl', () => {
         done();
       });
     });
+
+    it('should add maintenance name to trigger', (done) => {
+      zabbixDSMock.zabbix.getMaintenancesByIds = jest.fn().mockResolvedValue([{maintenanceid: '5', name: 'Upgrade'}]);
+      ctx.panelCtrl.datasources = { 'zabbix': zabbixDSMock };
+      let triggers = [
+        createTrigger({triggerid: "1", hosts: [{maintenance_status: '1', maintenanceid: '5'}]}),
+        createTrigger({triggerid: "2", hosts: [{maintenance_status: '0', maintenanceid: '0'}]}),
+      ];
+      ctx.panelCtrl.addMaintenanceNames(triggers, 'zabbix').then(result => {
+        expect(zabbixDSMock.zabbix.getMaintenancesByIds).toHaveBeenCalledWith(['5']);
+        expect(result[0].maintenanceName).toBe('Upgrade');
+        expect(result[1].maintenanceName).toBe(undefined);
+        done();
+      });
+    });
+
+    it('should keep triggers if maintenance names request failed', (done) => {
+      zabbixDSMock.zabbix.getMaintenancesByIds = jest.fn().mockRejectedValue(new Error('No permissions'));
+      ctx.panelCtrl.datasources = { 'zabbix': zabbixDSMock };
+      let triggers = [
+        createTrigger({triggerid: "1", hosts: [{maintenance_status: '1', maintenanceid: '5'}]}),
+      ];
+      ctx.panelCtrl.addMaintenanceNames(triggers, 'zabbix').then(result => {
+        expect(result.length).toBe(1);
+        expect(result[0].maintenanceName).toBe(undefined);
+        done();
+      });
+    });
   });
 
   describe('When formatting triggers', () => {
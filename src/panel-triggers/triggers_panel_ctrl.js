@@ -283,6 +283,7 @@ export class TriggerPanelCtrl extends PanelCtrl {
         return triggers;
       })
       .then(triggers => this.setMaintenanceStatus(triggers))
+      .then(triggers => this.addMaintenanceNames(triggers, ds))
       .then(triggers => this.setAckButtonStatus(triggers, showAckButton))
       .then(triggers => this.filterTriggersPre(triggers, ds))
       .then(triggers => this.addTriggerDataSource(triggers, ds))
@@ -399,6 +400,34 @@ export class TriggerPanelCtrl extends PanelCtrl {
     return triggers;
   }
 
+  addMaintenanceNames(triggers, ds) {
+    const maintenanceids = _.uniq(_.compact(_.flatMap(triggers, trigger => {
+      return _.map(_.filter(trigger.hosts, { maintenance_status: '1' }), 'maintenanceid');
+    })));
+    if (!maintenanceids.length) {
+      return triggers;
+    }
+
+    return this.datasources[ds].zabbix.getMaintenancesByIds(maintenanceids)
+    .then(maintenances => {
+      const maintenanceNames = _.mapValues(_.keyBy(maintenances, 'maintenanceid'), 'name');
+      _.each(triggers, trigger => {
+        const names = _.uniq(_.compact(_.map(trigger.hosts, host => {
+          return host.maintenance_status === '1' ? maintenanceNames[host.maintenanceid] : null;
+        })));
+        if (names.length) {
+          trigger.maintenanceName = names.join(', ');
+        }
+      });
+      return triggers;
+    })
+    .catch(err => {
+      // Names are optional, show plain maintenance badge if user can't read maintenances
+      console.log('Error getting maintenance names:', err);
+      return triggers;
+    });
+  }
+
   setAckButtonStatus(triggers, showAckButton) {
     _.each(triggers, (trigger) => {
       trigger.showAckButton = showAckButton;
@@ -598,7 +627,7 @@ export class TriggerPanelCtrl extends PanelCtrl {
     this.render();
   }
 
-  acknowledgeTrigger(trigger, message) {
+  acknowledgeTrigger(trigger, message, severity) {
     let eventid = trigger.lastEvent ? trigger.lastEvent.eventid : null;
     let grafana_user = this.contextSrv.user.name;
     let ack_message = grafana_user + ' (Grafana): ' + message;
@@ -609,7 +638,7 @@ export class TriggerPanelCtrl extends PanelCtrl {
         return Promise.reject({message: 'You have no permissions to acknowledge events.'});
       }
       if (eventid) {
-        return datasource.zabbix.acknowledgeEvent(eventid, ack_message);
+        return datasource.zabbix.acknowledgeEvent(eventid, ack_message, severity);
       } else {
         return Promise.reject({message: 'Trigger has no events. Nothing to acknowledge.'});
       }
@@ -671,8 +700,8 @@ export class TriggerPanelCtrl extends PanelCtrl {
         onPageSizeChange: ctrl.handlePageSizeChange.bind(ctrl),
         onColumnResize: ctrl.handleColumnResize.bind(ctrl),
         onProblemAck: (trigger, data) => {
-          const message = data.message;
-          return ctrl.acknowledgeTrigger(trigger, message);
+          const { message, severity } = data;
+          return ctrl.acknowledgeTrigger(trigger, message, severity);
         },
         onTagClick: (tag, datasource, ctrlKey, shiftKey) => {
           if (ctrlKey || shiftKey) {
@@ -96,6 +96,8 @@ export interface ZBXTrigger {
   lastchange?: string;
   lastchangeUnix?: number;
   maintenance?: boolean;
+  /** Name of active maintenance, if host is in maintenance. */
+  maintenanceName?: string;
   manual_close?: string;
   priority?: string;
   proxy?: string;
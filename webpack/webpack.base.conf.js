@@ -3,6 +3,8 @@ const webpack = require('webpack');
 const CopyWebpackPlugin = require('copy-webpack-plugin');
 const CleanWebpackPlugin = require('clean-webpack-plugin');
 const ExtractTextPlugin = require('extract-text-webpack-plugin');
+const childProcess = require('child_process');
+const pkg = require('../package.json');
 
 const ExtractTextPluginLight = new ExtractTextPlugin('./css/grafana-zabbix.light.css');
 const ExtractTextPluginDark = new ExtractTextPlugin('./css/grafana-zabbix.dark.css');
@@ -11,6 +13,22 @@ function resolve(dir) {
   return path.join(__dirname, '..', dir);
 }
 
+function getCommitHash() {
+  try {
+    return childProcess.execSync('git rev-parse --short HEAD').toString().trim();
+  } catch (err) {
+    return 'unknown';
+  }
+}
+
+// Fill build info placeholders in plugin.json, so version is kept in package.json only
+function replaceBuildInfo(content) {
+  return content.toString()
+  .replace(/%VERSION%/g, pkg.version)
+  .replace(/%COMMIT%/g, getCommitHash())
+  .replace(/%TODAY%/g, new Date().toISOString().substring(0, 10));
+}
+
 module.exports = {
   target: 'node',
   context: resolve('src'),
@@ -40,7 +58,7 @@ module.exports = {
   plugins: [
     new webpack.optimize.OccurrenceOrderPlugin(),
     new CopyWebpackPlugin([
-      { from: '**/plugin.json' },
+      { from: '**/plugin.json', transform: replaceBuildInfo },
       { from: '**/*.html' },
       { from: 'dashboards/*' },
       { from: '../README.md' },
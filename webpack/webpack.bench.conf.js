@@ -10,6 +10,7 @@ var conf = baseWebpackConfig;
 conf.mode = 'development';
 conf.entry = {
   'datasource-zabbix/benchmarks/timeseries_bench': './datasource-zabbix/benchmarks/timeseries_bench.js',
+  'datasource-zabbix/benchmarks/responseHandler_bench': './datasource-zabbix/benchmarks/responseHandler_bench.js',
 };
 conf.output = {
   filename: "[name].js",